@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/mhdph/go-start/internal/utils"
+)
+
+// defaultAnalyticsRange is how far back analytics go without a from date.
+const defaultAnalyticsRange = 90 * 24 * time.Hour
+
+type AnalyticsHandler struct {
+	analyticsStore store.AnalyticsStore
+	logger         *log.Logger
+}
+
+func NewAnalyticsHandler(analyticsStore store.AnalyticsStore, logger *log.Logger) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		analyticsStore: analyticsStore,
+		logger:         logger,
+	}
+}
+
+// HandleGetMyAnalytics serves ?bucket=day|week|month (default week) and
+// ?formula=epley|brzycki (default epley) between ?from and ?to.
+func (ah *AnalyticsHandler) HandleGetMyAnalytics(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	query := r.URL.Query()
+	q := store.AnalyticsQuery{
+		UserID:  int64(user.ID),
+		Bucket:  query.Get("bucket"),
+		Formula: query.Get("formula"),
+	}
+	if q.Bucket == "" {
+		q.Bucket = store.BucketWeek
+	}
+	if q.Formula == "" {
+		q.Formula = store.FormulaEpley
+	}
+
+	from, err := utils.ReadDateParam(r, "from")
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid from date"})
+		return
+	}
+	to, err := utils.ReadDateParam(r, "to")
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid to date"})
+		return
+	}
+
+	q.To = time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if to != nil {
+		q.To = to.Add(24 * time.Hour)
+	}
+	q.From = q.To.Add(-defaultAnalyticsRange)
+	if from != nil {
+		q.From = *from
+	}
+	if !q.From.Before(q.To) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid date range"})
+		return
+	}
+
+	analytics, err := ah.analyticsStore.GetAnalytics(q)
+	if errors.Is(err, store.ErrInvalidAnalyticsQuery) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "bucket must be day, week or month and formula epley or brzycki"})
+		return
+	}
+	if err != nil {
+		ah.logger.Printf("ERROR: getAnalytics: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	analytics.ConvertWeights(user.PreferredWeightUnit())
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"analytics": analytics})
+}
@@ -0,0 +1,246 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/mhdph/go-start/internal/utils"
+)
+
+type CommentHandler struct {
+	commentStore store.CommentStore
+	socialStore  store.SocialStore
+	workoutStore store.WorkoutStore
+	logger       *log.Logger
+}
+
+type createCommentRequest struct {
+	Body     string `json:"body"`
+	ParentID *int   `json:"parent_id"`
+}
+
+type reactionRequest struct {
+	Emoji string `json:"emoji"`
+}
+
+func NewCommentHandler(commentStore store.CommentStore, socialStore store.SocialStore, workoutStore store.WorkoutStore, logger *log.Logger) *CommentHandler {
+	return &CommentHandler{
+		commentStore: commentStore,
+		socialStore:  socialStore,
+		workoutStore: workoutStore,
+		logger:       logger,
+	}
+}
+
+func (ch *CommentHandler) HandleCreateComment(w http.ResponseWriter, r *http.Request) {
+	user, workout, ok := ch.loadVisibleWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	var req createCommentRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ch.logger.Printf("ERROR: decodingCreateComment: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	comment := &store.Comment{
+		WorkoutID: workout.ID,
+		ParentID:  req.ParentID,
+		Author:    store.UserSummary{ID: user.ID},
+		Body:      req.Body,
+	}
+	comment, err = ch.commentStore.CreateComment(comment)
+	if errors.Is(err, store.ErrInvalidComment) || errors.Is(err, store.ErrInvalidParent) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ch.logger.Printf("ERROR: createComment: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create comment"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, utils.Envelope{"comment": comment})
+}
+
+func (ch *CommentHandler) HandleGetComments(w http.ResponseWriter, r *http.Request) {
+	_, workout, ok := ch.loadVisibleWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	comments, err := ch.commentStore.GetComments(int64(workout.ID))
+	if err != nil {
+		ch.logger.Printf("ERROR: getComments: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"comments": comments})
+}
+
+// HandleDeleteComment lets the author delete their comment and the
+// workout's owner moderate any comment on it. Replies go with it.
+func (ch *CommentHandler) HandleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	user, workout, ok := ch.loadVisibleWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid comment id"})
+		return
+	}
+
+	comment, err := ch.commentStore.GetCommentByID(commentID)
+	if err != nil {
+		ch.logger.Printf("ERROR: getCommentByID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if comment == nil || comment.WorkoutID != workout.ID {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "comment not found"})
+		return
+	}
+	if comment.Author.ID != user.ID && workout.UserID != user.ID {
+		utils.WriteJson(w, http.StatusForbidden, utils.Envelope{"error": "only the author or the workout owner can delete this comment"})
+		return
+	}
+
+	err = ch.commentStore.DeleteComment(commentID)
+	if err == sql.ErrNoRows {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "comment not found"})
+		return
+	}
+	if err != nil {
+		ch.logger.Printf("ERROR: deleteComment: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to delete comment"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ch *CommentHandler) HandleAddReaction(w http.ResponseWriter, r *http.Request) {
+	user, workout, ok := ch.loadVisibleWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	var req reactionRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ch.logger.Printf("ERROR: decodingReaction: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	err = ch.commentStore.AddReaction(int64(workout.ID), user.ID, req.Emoji)
+	if errors.Is(err, store.ErrInvalidReaction) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error(), "allowed": store.Reactions})
+		return
+	}
+	if err != nil {
+		ch.logger.Printf("ERROR: addReaction: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to add reaction"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ch *CommentHandler) HandleGetReactions(w http.ResponseWriter, r *http.Request) {
+	_, workout, ok := ch.loadVisibleWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	reactions, err := ch.commentStore.GetReactions(int64(workout.ID))
+	if err != nil {
+		ch.logger.Printf("ERROR: getReactions: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"reactions": reactions, "counts": workout.Reactions})
+}
+
+// HandleRemoveReaction removes the user's ?emoji reaction from the workout.
+func (ch *CommentHandler) HandleRemoveReaction(w http.ResponseWriter, r *http.Request) {
+	user, workout, ok := ch.loadVisibleWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	err := ch.commentStore.RemoveReaction(int64(workout.ID), user.ID, r.URL.Query().Get("emoji"))
+	if err == sql.ErrNoRows {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "reaction not found"})
+		return
+	}
+	if err != nil {
+		ch.logger.Printf("ERROR: removeReaction: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to remove reaction"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (ch *CommentHandler) loadVisibleWorkout(w http.ResponseWriter, r *http.Request) (*store.User, *store.Workout, bool) {
+	return loadVisibleWorkout(w, r, ch.workoutStore, ch.socialStore, ch.logger)
+}
+
+// loadVisibleWorkout answers 404 rather than 403 for workouts the user may
+// not see, so private workouts do not leak their existence.
+func loadVisibleWorkout(w http.ResponseWriter, r *http.Request, workoutStore store.WorkoutStore, socialStore store.SocialStore, logger *log.Logger) (*store.User, *store.Workout, bool) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return nil, nil, false
+	}
+
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		logger.Printf("ERROR: readIDParam: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
+		return nil, nil, false
+	}
+
+	workout, err := workoutStore.GetWorkoutByID(workoutID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return nil, nil, false
+	}
+	if err != nil {
+		logger.Printf("ERROR: getWorkoutByID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, nil, false
+	}
+
+	following := false
+	if workout.UserID != user.ID && workout.Visibility == store.VisibilityFollowers {
+		following, err = socialStore.IsFollowing(user.ID, workout.UserID)
+		if err != nil {
+			logger.Printf("ERROR: isFollowing: %v", err)
+			utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+			return nil, nil, false
+		}
+	}
+	if !workout.CanView(user.ID, following) {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return nil, nil, false
+	}
+
+	return user, workout, true
+}
@@ -0,0 +1,220 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/mhdph/go-start/internal/utils"
+)
+
+type EntryHandler struct {
+	entryStore   store.EntryStore
+	workoutStore store.WorkoutStore
+	logger       *log.Logger
+}
+
+func NewEntryHandler(entryStore store.EntryStore, workoutStore store.WorkoutStore, logger *log.Logger) *EntryHandler {
+	return &EntryHandler{
+		entryStore:   entryStore,
+		workoutStore: workoutStore,
+		logger:       logger,
+	}
+}
+
+// HandleCreateEntry appends an entry to the workout. Weights without a
+// unit are read in the user's preferred unit.
+func (eh *EntryHandler) HandleCreateEntry(w http.ResponseWriter, r *http.Request) {
+	user, workout, ok := eh.loadOwnWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	var entry store.WorkoutEntry
+	err := json.NewDecoder(r.Body).Decode(&entry)
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request body"})
+		return
+	}
+	entry.ID = 0
+	if entry.WeightUnit == "" {
+		entry.WeightUnit = user.PreferredWeightUnit()
+	}
+
+	err = eh.entryStore.CreateEntry(workout, &entry, user.ID)
+	if !eh.checkEntryError(w, err, "createEntry") {
+		return
+	}
+
+	eh.writeEntry(w, http.StatusCreated, user, workout, entry.ID)
+}
+
+// HandleUpdateEntry replaces one entry, set log included, keeping its
+// position in the workout.
+func (eh *EntryHandler) HandleUpdateEntry(w http.ResponseWriter, r *http.Request) {
+	user, workout, ok := eh.loadOwnWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	entryID, ok := readEntryID(w, r)
+	if !ok {
+		return
+	}
+
+	var entry store.WorkoutEntry
+	err := json.NewDecoder(r.Body).Decode(&entry)
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request body"})
+		return
+	}
+	entry.ID = entryID
+	if entry.WeightUnit == "" {
+		entry.WeightUnit = user.PreferredWeightUnit()
+	}
+
+	err = eh.entryStore.UpdateEntry(workout, &entry, user.ID)
+	if !eh.checkEntryError(w, err, "updateEntry") {
+		return
+	}
+
+	eh.writeEntry(w, http.StatusOK, user, workout, entry.ID)
+}
+
+func (eh *EntryHandler) HandleDeleteEntry(w http.ResponseWriter, r *http.Request) {
+	user, workout, ok := eh.loadOwnWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	entryID, ok := readEntryID(w, r)
+	if !ok {
+		return
+	}
+
+	err := eh.entryStore.DeleteEntry(workout, entryID, user.ID)
+	if !eh.checkEntryError(w, err, "deleteEntry") {
+		return
+	}
+
+	w.Header().Set("ETag", workoutETag(workout, user.PreferredWeightUnit()))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleReorderEntries puts the workout's entries in the order of
+// "entry_ids", which must list each of them exactly once.
+func (eh *EntryHandler) HandleReorderEntries(w http.ResponseWriter, r *http.Request) {
+	user, workout, ok := eh.loadOwnWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		EntryIDs []int `json:"entry_ids"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	err = eh.entryStore.ReorderEntries(workout, req.EntryIDs, user.ID)
+	if errors.Is(err, store.ErrInvalidEntryOrder) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if !eh.checkEntryError(w, err, "reorderEntries") {
+		return
+	}
+
+	workout.ConvertWeights(user.PreferredWeightUnit())
+	w.Header().Set("ETag", workoutETag(workout, user.PreferredWeightUnit()))
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"entries": workout.Entries})
+}
+
+// loadOwnWorkout is the single ownership check for every entry endpoint:
+// entries are only reachable through a workout the user owns. Every entry
+// change is a write to the workout, so it needs an If-Match naming the
+// workout's current ETag.
+func (eh *EntryHandler) loadOwnWorkout(w http.ResponseWriter, r *http.Request) (*store.User, *store.Workout, bool) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return nil, nil, false
+	}
+
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		eh.logger.Printf("ERROR: readIDParam: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
+		return nil, nil, false
+	}
+
+	workout, err := eh.workoutStore.GetWorkoutByID(workoutID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		eh.logger.Printf("ERROR: getWorkoutByID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, nil, false
+	}
+
+	if workout == nil || workout.UserID != user.ID {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return nil, nil, false
+	}
+
+	if !checkIfMatch(w, r, workout) {
+		return nil, nil, false
+	}
+
+	return user, workout, true
+}
+
+// checkEntryError answers for a failed entry change and reports whether
+// the change went through.
+func (eh *EntryHandler) checkEntryError(w http.ResponseWriter, err error, op string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, sql.ErrNoRows):
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "entry not found"})
+	case errors.Is(err, store.ErrVersionConflict):
+		utils.WriteJson(w, http.StatusPreconditionFailed, utils.Envelope{"error": err.Error()})
+	case isInvalidWorkoutError(err):
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+	default:
+		eh.logger.Printf("ERROR: %s: %v", op, err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+	}
+	return false
+}
+
+func (eh *EntryHandler) writeEntry(w http.ResponseWriter, status int, user *store.User, workout *store.Workout, entryID int) {
+	workout.ConvertWeights(user.PreferredWeightUnit())
+	w.Header().Set("ETag", workoutETag(workout, user.PreferredWeightUnit()))
+
+	envelope := utils.Envelope{}
+	for _, entry := range workout.Entries {
+		if entry.ID == entryID {
+			envelope["entry"] = entry
+		}
+	}
+	if len(workout.NewRecords) > 0 {
+		envelope["new_records"] = workout.NewRecords
+	}
+	utils.WriteJson(w, status, envelope)
+}
+
+func readEntryID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	entryID, err := strconv.Atoi(chi.URLParam(r, "entryID"))
+	if err != nil || entryID < 1 {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "entry not found"})
+		return 0, false
+	}
+	return entryID, true
+}
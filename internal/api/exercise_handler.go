@@ -0,0 +1,173 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/mhdph/go-start/internal/utils"
+)
+
+type createExerciseRequest struct {
+	Name             string   `json:"name"`
+	Aliases          []string `json:"aliases"`
+	PrimaryMuscles   []string `json:"primary_muscles"`
+	SecondaryMuscles []string `json:"secondary_muscles"`
+	Equipment        string   `json:"equipment"`
+}
+
+type ExerciseHandler struct {
+	exerciseStore store.ExerciseStore
+	logger        *log.Logger
+}
+
+func NewExerciseHandler(exerciseStore store.ExerciseStore, logger *log.Logger) *ExerciseHandler {
+	return &ExerciseHandler{
+		exerciseStore: exerciseStore,
+		logger:        logger,
+	}
+}
+
+// HandleListExercises returns the catalog and the user's custom exercises,
+// optionally narrowed down by a ?q= search on names and aliases.
+func (eh *ExerciseHandler) HandleListExercises(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	exercises, err := eh.exerciseStore.GetExercisesForUser(int64(user.ID))
+	if err != nil {
+		eh.logger.Printf("ERROR: getExercisesForUser: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	if q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q"))); q != "" {
+		matches := []*store.Exercise{}
+		for _, exercise := range exercises {
+			if strings.Contains(strings.ToLower(exercise.Name), q) {
+				matches = append(matches, exercise)
+				continue
+			}
+			for _, alias := range exercise.Aliases {
+				if strings.Contains(alias, q) {
+					matches = append(matches, exercise)
+					break
+				}
+			}
+		}
+		exercises = matches
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"exercises": exercises})
+}
+
+func (eh *ExerciseHandler) HandleCreateExercise(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	var req createExerciseRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		eh.logger.Printf("ERROR: decode: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "name is required"})
+		return
+	}
+
+	userID := user.ID
+	exercise := &store.Exercise{
+		UserID:           &userID,
+		Name:             req.Name,
+		Aliases:          req.Aliases,
+		PrimaryMuscles:   req.PrimaryMuscles,
+		SecondaryMuscles: req.SecondaryMuscles,
+		Equipment:        req.Equipment,
+	}
+
+	createdExercise, err := eh.exerciseStore.CreateExercise(exercise)
+	if errors.Is(err, store.ErrDuplicateExercise) {
+		utils.WriteJson(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		eh.logger.Printf("ERROR: createExercise: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create exercise"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, utils.Envelope{"exercise": createdExercise})
+}
+
+func (eh *ExerciseHandler) HandleGetExerciseByID(w http.ResponseWriter, r *http.Request) {
+	exercise, ok := eh.loadVisibleExercise(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"exercise": exercise})
+}
+
+func (eh *ExerciseHandler) HandleDeleteExerciseByID(w http.ResponseWriter, r *http.Request) {
+	exercise, ok := eh.loadVisibleExercise(w, r)
+	if !ok {
+		return
+	}
+
+	if exercise.UserID == nil {
+		utils.WriteJson(w, http.StatusForbidden, utils.Envelope{"error": "catalog exercises cannot be deleted"})
+		return
+	}
+
+	err := eh.exerciseStore.DeleteExercise(int64(exercise.ID))
+	if err != nil {
+		eh.logger.Printf("ERROR: deleteExercise: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to delete exercise"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (eh *ExerciseHandler) loadVisibleExercise(w http.ResponseWriter, r *http.Request) (*store.Exercise, bool) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return nil, false
+	}
+
+	exerciseID, err := utils.ReadIDParam(r)
+	if err != nil {
+		eh.logger.Printf("ERROR: readIDParam: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
+		return nil, false
+	}
+
+	exercise, err := eh.exerciseStore.GetExerciseByID(exerciseID)
+	if err != nil {
+		eh.logger.Printf("ERROR: getExerciseByID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, false
+	}
+
+	if exercise == nil || (exercise.UserID != nil && *exercise.UserID != user.ID) {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "exercise not found"})
+		return nil, false
+	}
+
+	return exercise, true
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/mhdph/go-start/internal/utils"
+)
+
+type GoalHandler struct {
+	goalStore store.GoalStore
+	logger    *log.Logger
+}
+
+func NewGoalHandler(goalStore store.GoalStore, logger *log.Logger) *GoalHandler {
+	return &GoalHandler{
+		goalStore: goalStore,
+		logger:    logger,
+	}
+}
+
+// HandleGetMyGoals lists the user's goals with their progress as of now,
+// along with their training streak. ?grace_days (default 1) sets how many
+// rest days in a row the streak tolerates.
+func (gh *GoalHandler) HandleGetMyGoals(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	graceDays := store.DefaultGraceDays
+	if value := r.URL.Query().Get("grace_days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > store.MaxGraceDays {
+			utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid grace_days"})
+			return
+		}
+		graceDays = n
+	}
+
+	goals, err := gh.goalStore.GetGoals(int64(user.ID))
+	if err != nil {
+		gh.logger.Printf("ERROR: getGoals: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	now := time.Now().UTC()
+	activity, err := gh.goalStore.GetGoalActivity(int64(user.ID), goals, now)
+	if err != nil {
+		gh.logger.Printf("ERROR: getGoalActivity: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	for _, goal := range goals {
+		goal.Progress = store.ComputeProgress(goal, activity, now)
+		goal.ConvertWeights(user.PreferredWeightUnit())
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"goals":  goals,
+		"streak": store.ComputeStreak(activity.Days, graceDays, now),
+	})
+}
+
+// HandleCreateGoal sets a goal. Lift and volume targets are read in
+// "weight_unit", or the user's preferred unit; "deadline" is a date.
+func (gh *GoalHandler) HandleCreateGoal(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	var req struct {
+		Type         string  `json:"type"`
+		Target       float64 `json:"target"`
+		WeightUnit   string  `json:"weight_unit"`
+		ExerciseName string  `json:"exercise_name"`
+		Period       string  `json:"period"`
+		Deadline     string  `json:"deadline"`
+	}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	goal := &store.Goal{
+		UserID:       user.ID,
+		Type:         req.Type,
+		Target:       req.Target,
+		ExerciseName: req.ExerciseName,
+		Period:       req.Period,
+	}
+
+	if req.Deadline != "" {
+		deadline, err := time.Parse("2006-01-02", req.Deadline)
+		if err != nil {
+			utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "deadline must be a YYYY-MM-DD date"})
+			return
+		}
+		goal.Deadline = &deadline
+	}
+
+	if goal.Type != store.GoalTypeFrequency {
+		if req.WeightUnit == "" {
+			req.WeightUnit = user.PreferredWeightUnit()
+		}
+		if !store.ValidWeightUnit(req.WeightUnit) {
+			utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": store.ErrInvalidWeightUnit.Error()})
+			return
+		}
+		goal.Target = store.ToKilograms(goal.Target, req.WeightUnit)
+	}
+
+	goal, err = gh.goalStore.CreateGoal(goal)
+	if errors.Is(err, store.ErrInvalidGoal) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		gh.logger.Printf("ERROR: createGoal: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create goal"})
+		return
+	}
+
+	goal.ConvertWeights(user.PreferredWeightUnit())
+	utils.WriteJson(w, http.StatusCreated, utils.Envelope{"goal": goal})
+}
+
+func (gh *GoalHandler) HandleDeleteGoal(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	goalID, err := utils.ReadIDParam(r)
+	if err != nil {
+		gh.logger.Printf("ERROR: readIDParam: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
+		return
+	}
+
+	err = gh.goalStore.DeleteGoal(goalID, int64(user.ID))
+	if err == sql.ErrNoRows {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "goal not found"})
+		return
+	}
+	if err != nil {
+		gh.logger.Printf("ERROR: deleteGoal: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to delete goal"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
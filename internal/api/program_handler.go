@@ -0,0 +1,245 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/mhdph/go-start/internal/utils"
+)
+
+const maxCalendarDays = 366
+
+type createProgramRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	StartDate   string `json:"start_date"`
+	Weeks       int    `json:"weeks"`
+	Sessions    []struct {
+		TemplateID int  `json:"template_id"`
+		Weekday    int  `json:"weekday"`
+		Week       *int `json:"week"`
+	} `json:"sessions"`
+}
+
+type ProgramHandler struct {
+	programStore  store.ProgramStore
+	templateStore store.TemplateStore
+	logger        *log.Logger
+}
+
+func NewProgramHandler(programStore store.ProgramStore, templateStore store.TemplateStore, logger *log.Logger) *ProgramHandler {
+	return &ProgramHandler{
+		programStore:  programStore,
+		templateStore: templateStore,
+		logger:        logger,
+	}
+}
+
+func (ph *ProgramHandler) validateCreateProgramRequest(req *createProgramRequest) error {
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if req.Weeks < 1 {
+		return fmt.Errorf("weeks must be at least 1")
+	}
+	if len(req.Sessions) == 0 {
+		return fmt.Errorf("at least one session is required")
+	}
+	for i, session := range req.Sessions {
+		if session.Weekday < 0 || session.Weekday > 6 {
+			return fmt.Errorf("session %d: weekday must be between 0 (sunday) and 6 (saturday)", i)
+		}
+		if session.Week != nil && (*session.Week < 1 || *session.Week > req.Weeks) {
+			return fmt.Errorf("session %d: week must be between 1 and %d", i, req.Weeks)
+		}
+	}
+	return nil
+}
+
+func (ph *ProgramHandler) HandleCreateProgram(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	var req createProgramRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		ph.logger.Printf("ERROR: decode: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	err = ph.validateCreateProgramRequest(&req)
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	startDate, err := time.Parse("2006-01-02", req.StartDate)
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "start_date must be YYYY-MM-DD"})
+		return
+	}
+
+	program := &store.Program{
+		UserID:      user.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		StartDate:   startDate,
+		Weeks:       req.Weeks,
+	}
+
+	for _, session := range req.Sessions {
+		template, err := ph.templateStore.GetTemplateByID(int64(session.TemplateID))
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": fmt.Sprintf("template %d not found", session.TemplateID)})
+			return
+		}
+		if err != nil {
+			ph.logger.Printf("ERROR: getTemplateByID: %v", err)
+			utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+			return
+		}
+		if template.UserID != user.ID {
+			utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": fmt.Sprintf("template %d not found", session.TemplateID)})
+			return
+		}
+		program.Sessions = append(program.Sessions, store.ProgramSession{
+			TemplateID: template.ID,
+			Title:      template.Title,
+			Weekday:    time.Weekday(session.Weekday),
+			Week:       session.Week,
+		})
+	}
+
+	createdProgram, err := ph.programStore.CreateProgram(program)
+	if err != nil {
+		ph.logger.Printf("ERROR: createProgram: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create program"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, utils.Envelope{"program": createdProgram})
+}
+
+func (ph *ProgramHandler) HandleListPrograms(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	programs, err := ph.programStore.GetProgramsByUserID(int64(user.ID))
+	if err != nil {
+		ph.logger.Printf("ERROR: getProgramsByUserID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"programs": programs})
+}
+
+func (ph *ProgramHandler) HandleGetProgramByID(w http.ResponseWriter, r *http.Request) {
+	program, ok := ph.loadOwnProgram(w, r)
+	if !ok {
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"program": program})
+}
+
+func (ph *ProgramHandler) HandleDeleteProgramByID(w http.ResponseWriter, r *http.Request) {
+	program, ok := ph.loadOwnProgram(w, r)
+	if !ok {
+		return
+	}
+
+	err := ph.programStore.DeleteProgram(int64(program.ID))
+	if err != nil {
+		ph.logger.Printf("ERROR: deleteProgram: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to delete program"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetCalendar reports planned and logged sessions per day. Without
+// from/to it covers today only.
+func (ph *ProgramHandler) HandleGetCalendar(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	from, err := utils.ReadDateParam(r, "from")
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid from date"})
+		return
+	}
+	to, err := utils.ReadDateParam(r, "to")
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid to date"})
+		return
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	if from == nil {
+		from = &today
+	}
+	if to == nil {
+		to = from
+	}
+	if to.Before(*from) || to.Sub(*from) > maxCalendarDays*24*time.Hour {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid date range"})
+		return
+	}
+
+	days, err := ph.programStore.GetCalendar(int64(user.ID), *from, *to)
+	if err != nil {
+		ph.logger.Printf("ERROR: getCalendar: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"calendar": days})
+}
+
+func (ph *ProgramHandler) loadOwnProgram(w http.ResponseWriter, r *http.Request) (*store.Program, bool) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return nil, false
+	}
+
+	programID, err := utils.ReadIDParam(r)
+	if err != nil {
+		ph.logger.Printf("ERROR: readIDParam: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
+		return nil, false
+	}
+
+	program, err := ph.programStore.GetProgramByID(programID)
+	if err != nil {
+		ph.logger.Printf("ERROR: getProgramByID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, false
+	}
+
+	if program == nil || program.UserID != user.ID {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "program not found"})
+		return nil, false
+	}
+
+	return program, true
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/mhdph/go-start/internal/utils"
+)
+
+type RecordHandler struct {
+	recordStore store.RecordStore
+	logger      *log.Logger
+}
+
+func NewRecordHandler(recordStore store.RecordStore, logger *log.Logger) *RecordHandler {
+	return &RecordHandler{
+		recordStore: recordStore,
+		logger:      logger,
+	}
+}
+
+func (rh *RecordHandler) HandleGetMyRecords(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	records, err := rh.recordStore.GetRecordsByUserID(int64(user.ID))
+	if err != nil {
+		rh.logger.Printf("ERROR: getRecordsByUserID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	for _, record := range records {
+		record.ConvertWeights(user.PreferredWeightUnit())
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"records": records})
+}
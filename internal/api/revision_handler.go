@@ -0,0 +1,189 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/mhdph/go-start/internal/utils"
+)
+
+type RevisionHandler struct {
+	revisionStore store.RevisionStore
+	workoutStore  store.WorkoutStore
+	logger        *log.Logger
+}
+
+func NewRevisionHandler(revisionStore store.RevisionStore, workoutStore store.WorkoutStore, logger *log.Logger) *RevisionHandler {
+	return &RevisionHandler{
+		revisionStore: revisionStore,
+		workoutStore:  workoutStore,
+		logger:        logger,
+	}
+}
+
+func (rh *RevisionHandler) HandleListRevisions(w http.ResponseWriter, r *http.Request) {
+	_, workout, ok := rh.loadOwnWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := rh.revisionStore.GetRevisions(int64(workout.ID))
+	if err != nil {
+		rh.logger.Printf("ERROR: getRevisions: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"revisions": revisions})
+}
+
+func (rh *RevisionHandler) HandleGetRevision(w http.ResponseWriter, r *http.Request) {
+	user, workout, ok := rh.loadOwnWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	revision, ok := rh.loadRevision(w, workout, chi.URLParam(r, "revision"))
+	if !ok {
+		return
+	}
+
+	revision.Workout.ConvertWeights(user.PreferredWeightUnit())
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"revision": revision})
+}
+
+// HandleDiffRevisions compares ?from and ?to revisions of the workout.
+func (rh *RevisionHandler) HandleDiffRevisions(w http.ResponseWriter, r *http.Request) {
+	user, workout, ok := rh.loadOwnWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	from, ok := rh.loadRevision(w, workout, query.Get("from"))
+	if !ok {
+		return
+	}
+	to, ok := rh.loadRevision(w, workout, query.Get("to"))
+	if !ok {
+		return
+	}
+
+	from.Workout.ConvertWeights(user.PreferredWeightUnit())
+	to.Workout.ConvertWeights(user.PreferredWeightUnit())
+
+	changes, err := store.DiffWorkouts(from.Workout, to.Workout)
+	if err != nil {
+		rh.logger.Printf("ERROR: diffWorkouts: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"from":    from.Revision,
+		"to":      to.Revision,
+		"changes": changes,
+	})
+}
+
+// HandleRevertRevision restores the workout to a past revision. The revert
+// is itself recorded as a new revision, so it can be undone.
+func (rh *RevisionHandler) HandleRevertRevision(w http.ResponseWriter, r *http.Request) {
+	user, workout, ok := rh.loadOwnWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	revision, ok := rh.loadRevision(w, workout, chi.URLParam(r, "revision"))
+	if !ok {
+		return
+	}
+
+	snapshot := revision.Workout
+	workout.Title = snapshot.Title
+	workout.Description = snapshot.Description
+	workout.Duration = snapshot.Duration
+	workout.CaloriesBurned = snapshot.CaloriesBurned
+	workout.CaloriesEstimated = snapshot.CaloriesEstimated
+	workout.CaloriesReported = snapshot.CaloriesReported
+	workout.Visibility = snapshot.Visibility
+	workout.Tags = snapshot.Tags
+	workout.Entries = snapshot.Entries
+	for i := range workout.Entries {
+		workout.Entries[i].ID = 0
+	}
+
+	err := rh.workoutStore.UpdateWorkout(workout, user.ID)
+	if errors.Is(err, store.ErrVersionConflict) {
+		utils.WriteJson(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if isInvalidWorkoutError(err) {
+		utils.WriteJson(w, http.StatusConflict, utils.Envelope{"error": "revision can no longer be applied: " + err.Error()})
+		return
+	}
+	if err != nil {
+		rh.logger.Printf("ERROR: revertWorkout: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to revert workout"})
+		return
+	}
+
+	workout.ConvertWeights(user.PreferredWeightUnit())
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"workout": workout})
+}
+
+func (rh *RevisionHandler) loadRevision(w http.ResponseWriter, workout *store.Workout, param string) (*store.Revision, bool) {
+	number, err := strconv.Atoi(param)
+	if err != nil || number < 1 {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid revision"})
+		return nil, false
+	}
+
+	revision, err := rh.revisionStore.GetRevision(int64(workout.ID), number)
+	if err != nil {
+		rh.logger.Printf("ERROR: getRevision: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, false
+	}
+	if revision == nil {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "revision not found"})
+		return nil, false
+	}
+
+	return revision, true
+}
+
+func (rh *RevisionHandler) loadOwnWorkout(w http.ResponseWriter, r *http.Request) (*store.User, *store.Workout, bool) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return nil, nil, false
+	}
+
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		rh.logger.Printf("ERROR: readIDParam: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
+		return nil, nil, false
+	}
+
+	workout, err := rh.workoutStore.GetWorkoutByID(workoutID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		rh.logger.Printf("ERROR: getWorkoutByID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, nil, false
+	}
+
+	if workout == nil || workout.UserID != user.ID {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return nil, nil, false
+	}
+
+	return user, workout, true
+}
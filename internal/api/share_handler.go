@@ -0,0 +1,181 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/mhdph/go-start/internal/utils"
+)
+
+// maxShareExpiry caps how far ahead an owner can set a link to expire.
+const maxShareExpiry = 365 * 24 * time.Hour
+
+type ShareHandler struct {
+	shareStore   store.ShareStore
+	workoutStore store.WorkoutStore
+	logger       *log.Logger
+}
+
+type createShareRequest struct {
+	ExpiresInHours *int `json:"expires_in_hours"`
+}
+
+func NewShareHandler(shareStore store.ShareStore, workoutStore store.WorkoutStore, logger *log.Logger) *ShareHandler {
+	return &ShareHandler{
+		shareStore:   shareStore,
+		workoutStore: workoutStore,
+		logger:       logger,
+	}
+}
+
+// HandleCreateShare issues a share link for one of the user's workouts.
+// Creating a new link revokes the previous one.
+func (sh *ShareHandler) HandleCreateShare(w http.ResponseWriter, r *http.Request) {
+	workout, ok := sh.loadOwnWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	var req createShareRequest
+	if r.ContentLength != 0 {
+		err := json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			sh.logger.Printf("ERROR: decodingCreateShare: %v", err)
+			utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+			return
+		}
+	}
+
+	var expiry time.Duration
+	if req.ExpiresInHours != nil {
+		expiry = time.Duration(*req.ExpiresInHours) * time.Hour
+		if expiry <= 0 || expiry > maxShareExpiry {
+			utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "expires_in_hours must be between 1 and 8760"})
+			return
+		}
+	}
+
+	share, err := sh.shareStore.CreateShare(workout.ID, workout.UserID, expiry)
+	if err != nil {
+		sh.logger.Printf("ERROR: createShare: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create share link"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusCreated, utils.Envelope{"share": share, "url": "/shared/" + share.Token})
+}
+
+func (sh *ShareHandler) HandleGetShare(w http.ResponseWriter, r *http.Request) {
+	workout, ok := sh.loadOwnWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	share, err := sh.shareStore.GetShareByWorkoutID(int64(workout.ID))
+	if err != nil {
+		sh.logger.Printf("ERROR: getShareByWorkoutID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if share == nil {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout is not shared"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"share": share})
+}
+
+func (sh *ShareHandler) HandleRevokeShare(w http.ResponseWriter, r *http.Request) {
+	workout, ok := sh.loadOwnWorkout(w, r)
+	if !ok {
+		return
+	}
+
+	err := sh.shareStore.RevokeShare(int64(workout.ID))
+	if err == sql.ErrNoRows {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout is not shared"})
+		return
+	}
+	if err != nil {
+		sh.logger.Printf("ERROR: revokeShare: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to revoke share link"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetSharedWorkout is the public, read-only view behind a share
+// link. It needs no account; weights are shown in kilograms unless the
+// weight_unit query parameter asks for pounds.
+func (sh *ShareHandler) HandleGetSharedWorkout(w http.ResponseWriter, r *http.Request) {
+	weightUnit := r.URL.Query().Get("weight_unit")
+	if weightUnit == "" {
+		weightUnit = store.UnitKilograms
+	}
+	if !store.ValidWeightUnit(weightUnit) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": store.ErrInvalidWeightUnit.Error()})
+		return
+	}
+
+	workoutID, err := sh.shareStore.GetSharedWorkoutID(chi.URLParam(r, "token"))
+	if err != nil {
+		sh.logger.Printf("ERROR: getSharedWorkoutID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+	if workoutID == 0 {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "share link not found or expired"})
+		return
+	}
+
+	workout, err := sh.workoutStore.GetWorkoutByID(workoutID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "share link not found or expired"})
+		return
+	}
+	if err != nil {
+		sh.logger.Printf("ERROR: getWorkoutByID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	workout.ConvertWeights(weightUnit)
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"workout": workout})
+}
+
+func (sh *ShareHandler) loadOwnWorkout(w http.ResponseWriter, r *http.Request) (*store.Workout, bool) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return nil, false
+	}
+
+	workoutID, err := utils.ReadIDParam(r)
+	if err != nil {
+		sh.logger.Printf("ERROR: readIDParam: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
+		return nil, false
+	}
+
+	workout, err := sh.workoutStore.GetWorkoutByID(workoutID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		sh.logger.Printf("ERROR: getWorkoutByID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, false
+	}
+
+	if workout == nil || workout.UserID != user.ID {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return nil, false
+	}
+
+	return workout, true
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeShareStore keeps share links in memory, one per workout, and
+// follows the store in treating expired links as unknown.
+type fakeShareStore struct {
+	shares    map[int64]*store.WorkoutShare
+	nextToken int
+}
+
+func newFakeShareStore() *fakeShareStore {
+	return &fakeShareStore{shares: map[int64]*store.WorkoutShare{}}
+}
+
+func (f *fakeShareStore) CreateShare(workoutID, userID int, expiry time.Duration) (*store.WorkoutShare, error) {
+	f.nextToken++
+	share := &store.WorkoutShare{WorkoutID: workoutID, Token: fmt.Sprintf("token-%d", f.nextToken), CreatedAt: time.Now()}
+	if expiry > 0 {
+		at := time.Now().Add(expiry)
+		share.Expiry = &at
+	}
+	f.shares[int64(workoutID)] = share
+	return share, nil
+}
+
+func (f *fakeShareStore) GetShareByWorkoutID(workoutID int64) (*store.WorkoutShare, error) {
+	share, ok := f.shares[workoutID]
+	if !ok {
+		return nil, nil
+	}
+	found := *share
+	found.Token = ""
+	return &found, nil
+}
+
+func (f *fakeShareStore) GetSharedWorkoutID(tokenPlainText string) (int64, error) {
+	for workoutID, share := range f.shares {
+		if share.Token == tokenPlainText && (share.Expiry == nil || share.Expiry.After(time.Now())) {
+			return workoutID, nil
+		}
+	}
+	return 0, nil
+}
+
+func (f *fakeShareStore) RevokeShare(workoutID int64) error {
+	if _, ok := f.shares[workoutID]; !ok {
+		return sql.ErrNoRows
+	}
+	delete(f.shares, workoutID)
+	return nil
+}
+
+func newShareTestRouter(sh *ShareHandler, user *store.User) http.Handler {
+	r := chi.NewRouter()
+	r.Group(func(r chi.Router) {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, middleware.SetUser(r, user))
+			})
+		})
+		r.Get("/workouts/{id}/share", sh.HandleGetShare)
+		r.Post("/workouts/{id}/share", sh.HandleCreateShare)
+		r.Delete("/workouts/{id}/share", sh.HandleRevokeShare)
+	})
+	r.Get("/shared/{token}", sh.HandleGetSharedWorkout)
+	return r
+}
+
+func TestCreateShare(t *testing.T) {
+	workouts := newFakeWorkoutStore(
+		&store.Workout{ID: 7, UserID: 1, Title: "Push day"},
+		&store.Workout{ID: 8, UserID: 2, Title: "Not mine"},
+	)
+	shares := newFakeShareStore()
+	handler := newShareTestRouter(NewShareHandler(shares, workouts, log.New(io.Discard, "", 0)), &store.User{ID: 1})
+
+	tests := []struct {
+		name       string
+		target     string
+		body       string
+		wantStatus int
+		wantExpiry time.Duration
+	}{
+		{name: "no expiry", target: "/workouts/7/share", wantStatus: http.StatusCreated},
+		{name: "one hour", target: "/workouts/7/share", body: `{"expires_in_hours": 1}`, wantStatus: http.StatusCreated, wantExpiry: time.Hour},
+		{name: "longest expiry", target: "/workouts/7/share", body: `{"expires_in_hours": 8760}`, wantStatus: http.StatusCreated, wantExpiry: maxShareExpiry},
+		{name: "zero hours", target: "/workouts/7/share", body: `{"expires_in_hours": 0}`, wantStatus: http.StatusBadRequest},
+		{name: "negative hours", target: "/workouts/7/share", body: `{"expires_in_hours": -1}`, wantStatus: http.StatusBadRequest},
+		{name: "past the longest expiry", target: "/workouts/7/share", body: `{"expires_in_hours": 8761}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid body", target: "/workouts/7/share", body: `{"expires_in_hours": "soon"}`, wantStatus: http.StatusBadRequest},
+		{name: "another user's workout", target: "/workouts/8/share", wantStatus: http.StatusNotFound},
+		{name: "unknown workout", target: "/workouts/9/share", wantStatus: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := serve(handler, http.MethodPost, test.target, test.body, nil)
+			require.Equal(t, test.wantStatus, rec.Code, rec.Body.String())
+			if test.wantStatus != http.StatusCreated {
+				return
+			}
+
+			var body struct {
+				Share store.WorkoutShare `json:"share"`
+				URL   string             `json:"url"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, "/shared/"+body.Share.Token, body.URL)
+			if test.wantExpiry == 0 {
+				assert.Nil(t, body.Share.Expiry)
+				return
+			}
+			require.NotNil(t, body.Share.Expiry)
+			assert.WithinDuration(t, time.Now().Add(test.wantExpiry), *body.Share.Expiry, time.Minute)
+		})
+	}
+
+	assert.NotContains(t, shares.shares, int64(8))
+}
+
+func TestGetAndRevokeShare(t *testing.T) {
+	workouts := newFakeWorkoutStore(&store.Workout{ID: 7, UserID: 1, Title: "Push day"})
+	shares := newFakeShareStore()
+	sh := NewShareHandler(shares, workouts, log.New(io.Discard, "", 0))
+	handler := newShareTestRouter(sh, &store.User{ID: 1})
+
+	rec := serve(handler, http.MethodGet, "/workouts/7/share", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(handler, http.MethodDelete, "/workouts/7/share", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(handler, http.MethodPost, "/workouts/7/share", "", nil)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	rec = serve(handler, http.MethodGet, "/workouts/7/share", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "token-1", "the token is only shown once")
+
+	other := newShareTestRouter(sh, &store.User{ID: 2})
+	rec = serve(other, http.MethodDelete, "/workouts/7/share", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, shares.shares, int64(7))
+
+	rec = serve(handler, http.MethodDelete, "/workouts/7/share", "", nil)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = serve(handler, http.MethodDelete, "/workouts/7/share", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(handler, http.MethodGet, "/shared/token-1", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestGetSharedWorkout(t *testing.T) {
+	reps, weight := 5, 100.0
+	workouts := newFakeWorkoutStore(
+		&store.Workout{ID: 7, UserID: 1, Title: "Push day", Entries: []store.WorkoutEntry{
+			{ExerciesName: "Bench Press", Sets: 3, Reps: &reps, Weight: &weight, WeightUnit: store.UnitKilograms},
+		}},
+		&store.Workout{ID: 8, UserID: 1, Title: "Pull day"},
+	)
+	shares := newFakeShareStore()
+	expired := time.Now().Add(-time.Minute)
+	shares.shares[7] = &store.WorkoutShare{WorkoutID: 7, Token: "live"}
+	shares.shares[8] = &store.WorkoutShare{WorkoutID: 8, Token: "expired", Expiry: &expired}
+	shares.shares[9] = &store.WorkoutShare{WorkoutID: 9, Token: "deleted-workout"}
+
+	// the public view needs no user
+	handler := newShareTestRouter(NewShareHandler(shares, workouts, log.New(io.Discard, "", 0)), nil)
+
+	tests := []struct {
+		name       string
+		target     string
+		wantStatus int
+		wantWeight float64
+	}{
+		{name: "live link", target: "/shared/live", wantStatus: http.StatusOK, wantWeight: 100},
+		{name: "in pounds", target: "/shared/live?weight_unit=lb", wantStatus: http.StatusOK, wantWeight: 220.46},
+		{name: "unknown unit", target: "/shared/live?weight_unit=stone", wantStatus: http.StatusBadRequest},
+		{name: "unknown token", target: "/shared/nope", wantStatus: http.StatusNotFound},
+		{name: "expired link", target: "/shared/expired", wantStatus: http.StatusNotFound},
+		{name: "deleted workout", target: "/shared/deleted-workout", wantStatus: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rec := serve(handler, http.MethodGet, test.target, "", nil)
+			require.Equal(t, test.wantStatus, rec.Code, rec.Body.String())
+			if test.wantStatus != http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Workout store.Workout `json:"workout"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, "Push day", body.Workout.Title)
+			require.Len(t, body.Workout.Entries, 1)
+			assert.Equal(t, test.wantWeight, *body.Workout.Entries[0].Weight)
+		})
+	}
+}
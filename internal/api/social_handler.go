@@ -0,0 +1,159 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/mhdph/go-start/internal/utils"
+)
+
+type SocialHandler struct {
+	socialStore store.SocialStore
+	logger      *log.Logger
+}
+
+func NewSocialHandler(socialStore store.SocialStore, logger *log.Logger) *SocialHandler {
+	return &SocialHandler{
+		socialStore: socialStore,
+		logger:      logger,
+	}
+}
+
+func (sh *SocialHandler) HandleFollowUser(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	followeeID, err := utils.ReadIDParam(r)
+	if err != nil {
+		sh.logger.Printf("ERROR: readIDParam: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
+		return
+	}
+
+	err = sh.socialStore.Follow(user.ID, int(followeeID))
+	if errors.Is(err, store.ErrUserNotFound) {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, store.ErrSelfFollow) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		sh.logger.Printf("ERROR: follow: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to follow user"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (sh *SocialHandler) HandleUnfollowUser(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	followeeID, err := utils.ReadIDParam(r)
+	if err != nil {
+		sh.logger.Printf("ERROR: readIDParam: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
+		return
+	}
+
+	err = sh.socialStore.Unfollow(user.ID, int(followeeID))
+	if err == sql.ErrNoRows {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "not following this user"})
+		return
+	}
+	if err != nil {
+		sh.logger.Printf("ERROR: unfollow: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to unfollow user"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (sh *SocialHandler) HandleGetFollowers(w http.ResponseWriter, r *http.Request) {
+	sh.handleListFollows(w, r, "followers", sh.socialStore.GetFollowers)
+}
+
+func (sh *SocialHandler) HandleGetFollowing(w http.ResponseWriter, r *http.Request) {
+	sh.handleListFollows(w, r, "following", sh.socialStore.GetFollowing)
+}
+
+func (sh *SocialHandler) handleListFollows(w http.ResponseWriter, r *http.Request, key string, list func(userID int) ([]store.Follow, error)) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	userID, err := utils.ReadIDParam(r)
+	if err != nil {
+		sh.logger.Printf("ERROR: readIDParam: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
+		return
+	}
+
+	follows, err := list(int(userID))
+	if err != nil {
+		sh.logger.Printf("ERROR: list %s: %v", key, err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{key: follows})
+}
+
+// HandleGetFeed lists recent workouts of the people the user follows,
+// newest first. Pass next_cursor back as ?cursor for the next page.
+func (sh *SocialHandler) HandleGetFeed(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	query := r.URL.Query()
+	feedQuery := store.FeedQuery{
+		UserID: user.ID,
+		Cursor: query.Get("cursor"),
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > store.MaxListLimit {
+			utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid limit"})
+			return
+		}
+		feedQuery.Limit = n
+	}
+
+	items, nextCursor, err := sh.socialStore.GetFeed(feedQuery)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		sh.logger.Printf("ERROR: getFeed: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to load feed"})
+		return
+	}
+
+	for _, item := range items {
+		item.Workout.ConvertWeights(user.PreferredWeightUnit())
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"items": items, "next_cursor": nextCursor})
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/mhdph/go-start/internal/utils"
+)
+
+// maxRetagWorkouts caps how many workouts one bulk retag can touch.
+const maxRetagWorkouts = 500
+
+type TagHandler struct {
+	tagStore store.TagStore
+	logger   *log.Logger
+}
+
+type retagRequest struct {
+	WorkoutIDs []int64  `json:"workout_ids"`
+	Add        []string `json:"add"`
+	Remove     []string `json:"remove"`
+}
+
+func NewTagHandler(tagStore store.TagStore, logger *log.Logger) *TagHandler {
+	return &TagHandler{
+		tagStore: tagStore,
+		logger:   logger,
+	}
+}
+
+// HandleListTags autocompletes the user's tags from ?prefix, most used
+// first.
+func (th *TagHandler) HandleListTags(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	query := r.URL.Query()
+	limit := store.DefaultTagLimit
+	if value := query.Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > store.MaxListLimit {
+			utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	tags, err := th.tagStore.GetTags(user.ID, query.Get("prefix"), limit)
+	if err != nil {
+		th.logger.Printf("ERROR: getTags: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"tags": tags})
+}
+
+// HandleRetag adds and removes tags on a batch of the user's workouts.
+// Workouts the user does not own are ignored.
+func (th *TagHandler) HandleRetag(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	var req retagRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		th.logger.Printf("ERROR: decodingRetag: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request payload"})
+		return
+	}
+
+	if len(req.WorkoutIDs) == 0 || len(req.WorkoutIDs) > maxRetagWorkouts {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "workout_ids must list between 1 and 500 workouts"})
+		return
+	}
+	if len(req.Add) == 0 && len(req.Remove) == 0 {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "nothing to add or remove"})
+		return
+	}
+
+	updated, err := th.tagStore.Retag(store.Retag{
+		UserID:     user.ID,
+		WorkoutIDs: req.WorkoutIDs,
+		Add:        req.Add,
+		Remove:     req.Remove,
+	})
+	if errors.Is(err, store.ErrInvalidTag) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		th.logger.Printf("ERROR: retag: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to retag workouts"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"updated": updated})
+}
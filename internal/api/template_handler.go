@@ -0,0 +1,217 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/mhdph/go-start/internal/utils"
+)
+
+type createTemplateRequest struct {
+	Name           string               `json:"name"`
+	WorkoutID      *int64               `json:"workout_id"`
+	Title          string               `json:"title"`
+	Description    string               `json:"description"`
+	Duration       int                  `json:"duration"`
+	CaloriesBurned int                  `json:"calories_burned"`
+	Entries        []store.WorkoutEntry `json:"entries"`
+}
+
+type TemplateHandler struct {
+	templateStore store.TemplateStore
+	workoutStore  store.WorkoutStore
+	logger        *log.Logger
+}
+
+func NewTemplateHandler(templateStore store.TemplateStore, workoutStore store.WorkoutStore, logger *log.Logger) *TemplateHandler {
+	return &TemplateHandler{
+		templateStore: templateStore,
+		workoutStore:  workoutStore,
+		logger:        logger,
+	}
+}
+
+func (th *TemplateHandler) HandleCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	var req createTemplateRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		th.logger.Printf("ERROR: decode: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	if req.Name == "" {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "name is required"})
+		return
+	}
+
+	var template *store.WorkoutTemplate
+	if req.WorkoutID != nil {
+		workout, err := th.workoutStore.GetWorkoutByID(*req.WorkoutID)
+		if errors.Is(err, sql.ErrNoRows) {
+			utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+			return
+		}
+		if err != nil {
+			th.logger.Printf("ERROR: getWorkoutByID: %v", err)
+			utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+			return
+		}
+		if workout.UserID != user.ID {
+			utils.WriteJson(w, http.StatusForbidden, utils.Envelope{"error": "you can only save your own workouts as templates"})
+			return
+		}
+		template = store.NewTemplateFromWorkout(req.Name, workout)
+	} else {
+		if req.Title == "" {
+			utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "title is required"})
+			return
+		}
+		template = &store.WorkoutTemplate{
+			UserID:         user.ID,
+			Name:           req.Name,
+			Title:          req.Title,
+			Description:    req.Description,
+			Duration:       req.Duration,
+			CaloriesBurned: req.CaloriesBurned,
+			Entries:        req.Entries,
+		}
+		store.SetDefaultWeightUnit(template.Entries, user.PreferredWeightUnit())
+	}
+
+	createdTemplate, err := th.templateStore.CreateTemplate(template)
+	if isInvalidWorkoutError(err) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, store.ErrDuplicateTemplate) {
+		utils.WriteJson(w, http.StatusConflict, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		th.logger.Printf("ERROR: createTemplate: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to create template"})
+		return
+	}
+
+	createdTemplate.ConvertWeights(user.PreferredWeightUnit())
+
+	utils.WriteJson(w, http.StatusCreated, utils.Envelope{"template": createdTemplate})
+}
+
+func (th *TemplateHandler) HandleListTemplates(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	templates, err := th.templateStore.GetTemplatesByUserID(int64(user.ID))
+	if err != nil {
+		th.logger.Printf("ERROR: getTemplatesByUserID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"templates": templates})
+}
+
+func (th *TemplateHandler) HandleGetTemplateByID(w http.ResponseWriter, r *http.Request) {
+	template, ok := th.loadOwnTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	template.ConvertWeights(middleware.GetUser(r).PreferredWeightUnit())
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"template": template})
+}
+
+func (th *TemplateHandler) HandleDeleteTemplateByID(w http.ResponseWriter, r *http.Request) {
+	template, ok := th.loadOwnTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	err := th.templateStore.DeleteTemplate(int64(template.ID))
+	if err != nil {
+		th.logger.Printf("ERROR: deleteTemplate: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to delete template"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleStartTemplate creates a new workout for the current user from a template.
+func (th *TemplateHandler) HandleStartTemplate(w http.ResponseWriter, r *http.Request) {
+	template, ok := th.loadOwnTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	user := middleware.GetUser(r)
+	workout := template.ToWorkout(user.ID)
+	workout.EstimateCalories(user.BodyWeight)
+
+	workout, err := th.workoutStore.CreateWorkOut(workout)
+	if isInvalidWorkoutError(err) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		th.logger.Printf("ERROR: createWorkout from template: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to start workout"})
+		return
+	}
+
+	workout.ConvertWeights(user.PreferredWeightUnit())
+
+	utils.WriteJson(w, http.StatusCreated, utils.Envelope{"workout": workout})
+}
+
+// loadOwnTemplate reads the {id} template and makes sure it belongs to the
+// current user, writing the error response itself when it does not.
+func (th *TemplateHandler) loadOwnTemplate(w http.ResponseWriter, r *http.Request) (*store.WorkoutTemplate, bool) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return nil, false
+	}
+
+	templateID, err := utils.ReadIDParam(r)
+	if err != nil {
+		th.logger.Printf("ERROR: readIDParam: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
+		return nil, false
+	}
+
+	template, err := th.templateStore.GetTemplateByID(templateID)
+	if errors.Is(err, sql.ErrNoRows) {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "template not found"})
+		return nil, false
+	}
+	if err != nil {
+		th.logger.Printf("ERROR: getTemplateByID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
+		return nil, false
+	}
+
+	if template.UserID != user.ID {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "template not found"})
+		return nil, false
+	}
+
+	return template, true
+}
@@ -7,15 +7,24 @@ import (
 	"net/http"
 	"regexp"
 
+	"github.com/mhdph/go-start/internal/middleware"
 	"github.com/mhdph/go-start/internal/store"
 	"github.com/mhdph/go-start/internal/utils"
 )
 
 type registerUserRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Email    string `json:"email"`
-	Bio      string `json:"bio"`
+	Username   string `json:"username"`
+	Password   string `json:"password"`
+	Email      string `json:"email"`
+	Bio        string `json:"bio"`
+	WeightUnit string `json:"weight_unit"`
+}
+
+// updatePreferencesRequest only changes the fields that are present. The
+// body weight is read in the (new) preferred weight unit.
+type updatePreferencesRequest struct {
+	WeightUnit *string  `json:"weight_unit"`
+	BodyWeight *float64 `json:"body_weight"`
 }
 
 type UserHandler struct {
@@ -41,6 +50,10 @@ func (h *UserHandler) validateRegisterUserRequest(req *registerUserRequest) erro
 		return errors.New("email is required")
 	}
 
+	if req.WeightUnit != "" && !store.ValidWeightUnit(req.WeightUnit) {
+		return store.ErrInvalidWeightUnit
+	}
+
 	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 	if !emailRegex.MatchString(req.Email) {
 		return errors.New("invalid email address")
@@ -64,9 +77,10 @@ func (h *UserHandler) HandleRegisterUser(w http.ResponseWriter, r *http.Request)
 	}
 
 	user := &store.User{
-		Username: req.Username,
-		Email:    req.Email,
-		Bio:      req.Bio,
+		Username:   req.Username,
+		Email:      req.Email,
+		Bio:        req.Bio,
+		WeightUnit: req.WeightUnit,
 	}
 
 	err = user.Password.Set(req.Password)
@@ -85,3 +99,50 @@ func (h *UserHandler) HandleRegisterUser(w http.ResponseWriter, r *http.Request)
 	}
 	utils.WriteJson(w, http.StatusCreated, utils.Envelope{"message": "user created successfully"})
 }
+
+func (h *UserHandler) HandleUpdatePreferences(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	var req updatePreferencesRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		h.logger.Printf("ERROR: decode: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	updated := *user
+	updated.WeightUnit = user.PreferredWeightUnit()
+	if req.WeightUnit != nil {
+		if !store.ValidWeightUnit(*req.WeightUnit) {
+			utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": store.ErrInvalidWeightUnit.Error()})
+			return
+		}
+		updated.WeightUnit = *req.WeightUnit
+	}
+	if req.BodyWeight != nil {
+		if *req.BodyWeight <= 0 {
+			utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "body_weight must be positive"})
+			return
+		}
+		bodyWeight := store.ToKilograms(*req.BodyWeight, updated.WeightUnit)
+		updated.BodyWeight = &bodyWeight
+	}
+
+	err = h.userStore.UpdatePreferences(&updated)
+	if err != nil {
+		h.logger.Printf("ERROR: update preferences: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to update preferences"})
+		return
+	}
+
+	preferences := utils.Envelope{"weight_unit": updated.WeightUnit, "body_weight": nil}
+	if updated.BodyWeight != nil {
+		preferences["body_weight"] = store.FromKilograms(*updated.BodyWeight, updated.WeightUnit)
+	}
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"preferences": preferences})
+}
@@ -1,44 +1,297 @@
 package api
 
 import (
+	"bytes"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
 	"log"
+	"mime"
 	"net/http"
+	"reflect"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/go-chi/chi/v5"
+	"github.com/mhdph/go-start/internal/export"
+	"github.com/mhdph/go-start/internal/importer"
+	"github.com/mhdph/go-start/internal/jsonpatch"
 	"github.com/mhdph/go-start/internal/middleware"
 	"github.com/mhdph/go-start/internal/store"
 	"github.com/mhdph/go-start/internal/utils"
 )
 
+// maxImportSize caps the size of an uploaded history file.
+const maxImportSize = 10 << 20
+
+// maxPatchSize caps the size of a patch document.
+const maxPatchSize = 1 << 20
+
+// exportPageTimeout is how long one page of an export may take to write.
+const exportPageTimeout = 30 * time.Second
+
 type WorkoutHandler struct {
 	workoutStore store.WorkoutStore
+	socialStore  store.SocialStore
 	logger       *log.Logger
 }
 
-func NewWorkoutHandler(workoutStore store.WorkoutStore, logger *log.Logger) *WorkoutHandler {
+func NewWorkoutHandler(workoutStore store.WorkoutStore, socialStore store.SocialStore, logger *log.Logger) *WorkoutHandler {
 	return &WorkoutHandler{
 		workoutStore: workoutStore,
+		socialStore:  socialStore,
 		logger:       logger,
 	}
 
 }
 
 func (wh *WorkoutHandler) HandleGetWorkoutByID(w http.ResponseWriter, r *http.Request) {
-	workoutId, err := utils.ReadIDParam(r)
+	user, workout, ok := loadVisibleWorkout(w, r, wh.workoutStore, wh.socialStore, wh.logger)
+	if !ok {
+		return
+	}
+
+	unit := user.PreferredWeightUnit()
+	etag := workoutETag(workout, unit)
+	w.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && utils.ETagMatches(match, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	workout.ConvertWeights(unit)
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"workout": workout})
+}
+
+func (wh *WorkoutHandler) HandleListWorkouts(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	query := r.URL.Query()
+	filter := store.WorkoutFilter{
+		UserID:         int64(user.ID),
+		Title:          query.Get("title"),
+		Tags:           query["tag"],
+		TagMode:        query.Get("tag_mode"),
+		Sort:           query.Get("sort"),
+		Cursor:         query.Get("cursor"),
+		IncludeEntries: query.Get("include") == "entries",
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > store.MaxListLimit {
+			utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid limit"})
+			return
+		}
+		filter.Limit = n
+	}
+
+	from, err := utils.ReadDateParam(r, "from")
 	if err != nil {
-		wh.logger.Printf("ERROR: readIDPram: %v", err)
-		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid from date"})
 		return
 	}
-	workout, err := wh.workoutStore.GetWorkoutByID(workoutId)
+	filter.From = from
+
+	to, err := utils.ReadDateParam(r, "to")
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid to date"})
 		return
 	}
+	if to != nil {
+		// the to date is inclusive, so stop at the start of the next day
+		end := to.Add(24 * time.Hour)
+		filter.To = &end
+	}
 
-	utils.WriteJson(w, http.StatusOK, utils.Envelope{"workout": workout})
+	workouts, nextCursor, err := wh.workoutStore.ListWorkouts(filter)
+	if errors.Is(err, store.ErrInvalidCursor) || errors.Is(err, store.ErrInvalidSort) ||
+		errors.Is(err, store.ErrInvalidTag) || errors.Is(err, store.ErrInvalidTagMode) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: listWorkouts: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to list workouts"})
+		return
+	}
+
+	for _, workout := range workouts {
+		workout.ConvertWeights(user.PreferredWeightUnit())
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"workouts": workouts, "next_cursor": nextCursor})
+}
+
+// HandleExportWorkouts streams the user's history, entries included, as
+// ?format=csv|jsonl|ics between the optional ?from and ?to dates. Workouts
+// are fetched a page at a time so the whole history is never in memory.
+func (wh *WorkoutHandler) HandleExportWorkouts(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = export.FormatCSV
+	}
+	if !export.ValidFormat(format) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": export.ErrUnknownFormat.Error()})
+		return
+	}
+
+	filter := store.WorkoutFilter{
+		UserID:         int64(user.ID),
+		Sort:           store.SortOldest,
+		Limit:          store.MaxListLimit,
+		IncludeEntries: true,
+	}
+
+	from, err := utils.ReadDateParam(r, "from")
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid from date"})
+		return
+	}
+	filter.From = from
+
+	to, err := utils.ReadDateParam(r, "to")
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid to date"})
+		return
+	}
+	if to != nil {
+		end := to.Add(24 * time.Hour)
+		filter.To = &end
+	}
+
+	// fetch the first page before writing anything so that errors can
+	// still be reported with a proper status code
+	workouts, nextCursor, err := wh.workoutStore.ListWorkouts(filter)
+	if err != nil {
+		wh.logger.Printf("ERROR: export listWorkouts: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to export workouts"})
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="workouts.%s"`, format))
+	writer, err := export.NewWriter(format, w)
+	if err != nil {
+		wh.logger.Printf("ERROR: export: %v", err)
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	flusher, _ := w.(http.Flusher)
+	for {
+		// the server's WriteTimeout would cut a long history off midway, so
+		// every page gets a fresh deadline
+		err = controller.SetWriteDeadline(time.Now().Add(exportPageTimeout))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			wh.logger.Printf("ERROR: export setWriteDeadline: %v", err)
+			return
+		}
+
+		for _, workout := range workouts {
+			workout.ConvertWeights(user.PreferredWeightUnit())
+			err = writer.Write(workout)
+			if err != nil {
+				wh.logger.Printf("ERROR: export write: %v", err)
+				return
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if nextCursor == "" {
+			break
+		}
+		filter.Cursor = nextCursor
+		workouts, nextCursor, err = wh.workoutStore.ListWorkouts(filter)
+		if err != nil {
+			wh.logger.Printf("ERROR: export listWorkouts: %v", err)
+			return
+		}
+	}
+
+	err = writer.Close()
+	if err != nil {
+		wh.logger.Printf("ERROR: export close: %v", err)
+	}
+}
+
+// HandleImportWorkouts imports a Strong or Hevy CSV export, or a GPX or TCX
+// recording, uploaded as the "file" multipart field. Weights without a
+// unit in the file are read in the "weight_unit" field, or the user's
+// preferred unit. The whole file is imported in one transaction; rows that
+// cannot be parsed are skipped and reported back.
+func (wh *WorkoutHandler) HandleImportWorkouts(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		wh.logger.Printf("ERROR: import formFile: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "a CSV, GPX or TCX file is required in the file field"})
+		return
+	}
+	defer file.Close()
+
+	weightUnit := r.FormValue("weight_unit")
+	if weightUnit == "" {
+		weightUnit = user.PreferredWeightUnit()
+	}
+	if !store.ValidWeightUnit(weightUnit) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": store.ErrInvalidWeightUnit.Error()})
+		return
+	}
+
+	result, err := importer.ParseFile(file, header.Filename, weightUnit)
+	if err != nil {
+		wh.logger.Printf("ERROR: import parse: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	// a workout that would not save is reported like a bad row instead of
+	// failing the whole file
+	result.DropInvalid()
+	for _, workout := range result.Workouts {
+		workout.EstimateCalories(user.BodyWeight)
+	}
+
+	imported, err := wh.workoutStore.ImportWorkouts(user.ID, result.Workouts)
+	if isInvalidWorkoutError(err) {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": err.Error(), "errors": result.Errors})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: importWorkouts: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to import workouts"})
+		return
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{
+		"format":             result.Format,
+		"imported":           len(imported),
+		"skipped_duplicates": len(result.Workouts) - len(imported),
+		"errors":             result.Errors,
+	})
 }
 
 func (wh *WorkoutHandler) HandleCreateWorkout(w http.ResponseWriter, r *http.Request) {
@@ -50,126 +303,621 @@ func (wh *WorkoutHandler) HandleCreateWorkout(w http.ResponseWriter, r *http.Req
 	}
 
 	user := middleware.GetUser(r)
-	if user == nil {
+	if user == nil || user.IsAnnoymous() {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	workout.UserID = user.ID
+	prepareNewWorkout(&workout, user)
 
 	createdWorkout, err := wh.workoutStore.CreateWorkOut(&workout)
+	if isInvalidWorkoutError(err) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	createdWorkout.ConvertWeights(user.PreferredWeightUnit())
+
+	w.Header().Set("ETag", workoutETag(createdWorkout, user.PreferredWeightUnit()))
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(createdWorkout)
 }
-func (wh *WorkoutHandler) HandleUpdateWorkoutById(w http.ResponseWriter, r *http.Request) {
-	paramsWorkoutId := chi.URLParam(r, "id")
 
-	if paramsWorkoutId == "" {
-		http.NotFound(w, r)
+// HandleUpdateWorkoutById patches a workout with either a JSON Merge Patch
+// (application/merge-patch+json) or a JSON Patch
+// (application/json-patch+json) applied to the workout as GET returns it,
+// weights in the user's unit. Plain application/json is read as a merge
+// patch. The patched workout is validated as a whole before it is saved.
+func (wh *WorkoutHandler) HandleUpdateWorkoutById(w http.ResponseWriter, r *http.Request) {
+	apply, ok := patchFunc(r.Header.Get("Content-Type"))
+	if !ok {
+		w.Header().Set("Accept-Patch", jsonpatch.MediaTypeMergePatch+", "+jsonpatch.MediaTypeJSONPatch)
+		utils.WriteJson(w, http.StatusUnsupportedMediaType, utils.Envelope{"error": "unsupported patch format"})
 		return
 	}
+	wh.changeWorkout(w, r, apply)
+}
 
-	workoutId, err := strconv.ParseInt(paramsWorkoutId, 10, 64)
+// HandleReplaceWorkoutById replaces a workout with the one in the body,
+// weights in the user's unit. Fields left out are cleared, while the
+// fields the server manages keep their stored values.
+func (wh *WorkoutHandler) HandleReplaceWorkoutById(w http.ResponseWriter, r *http.Request) {
+	wh.changeWorkout(w, r, replaceDocument)
+}
+
+// changeWorkout saves the result of apply, given the stored workout as GET
+// returns it and the request body, if the request's If-Match is current.
+func (wh *WorkoutHandler) changeWorkout(w http.ResponseWriter, r *http.Request, apply func(doc, body []byte) ([]byte, error)) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
 
+	workoutId, err := utils.ReadIDParam(r)
 	if err != nil {
-		http.NotFound(w, r)
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
 		return
 	}
 
 	existingWorkout, err := wh.workoutStore.GetWorkoutByID(workoutId)
+	if err == sql.ErrNoRows || (err == nil && existingWorkout.UserID != user.ID) {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: getWorkoutByID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to fetch workout"})
+		return
+	}
 
+	if !checkIfMatch(w, r, existingWorkout) {
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request body"})
+		return
+	}
+
+	workout, status, err := patchWorkout(existingWorkout, patch, apply, user)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			wh.logger.Printf("ERROR: patchWorkout: %v", err)
+			utils.WriteJson(w, status, utils.Envelope{"error": "failed to update workout"})
+			return
+		}
+		utils.WriteJson(w, status, utils.Envelope{"error": err.Error()})
+		return
+	}
+
+	err = wh.workoutStore.UpdateWorkout(workout, user.ID)
+	if isInvalidWorkoutError(err) {
+		utils.WriteJson(w, http.StatusUnprocessableEntity, utils.Envelope{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		utils.WriteJson(w, http.StatusPreconditionFailed, utils.Envelope{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		http.Error(w, "failed to fetch workout", http.StatusInternalServerError)
+		wh.logger.Printf("ERROR: updateWorkout: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to update workout"})
+		return
+	}
+	workout.ConvertWeights(user.PreferredWeightUnit())
+
+	w.Header().Set("ETag", workoutETag(workout, user.PreferredWeightUnit()))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(workout)
+}
+
+type batchOperation struct {
+	Op      string          `json:"op"`
+	ID      int64           `json:"id"`
+	Ref     *int            `json:"ref"`
+	Version int             `json:"version"`
+	Workout json.RawMessage `json:"workout"`
+}
+
+type batchResult struct {
+	Index   int            `json:"index"`
+	Op      string         `json:"op"`
+	Status  int            `json:"status"`
+	ID      int64          `json:"id,omitempty"`
+	Workout *store.Workout `json:"workout,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// HandleBatchWorkouts applies up to store.MaxBatchSize creates, updates and
+// deletes in order, as "operations" of {"op", "id", "ref", "version",
+// "workout"}. Creates take a workout as POST /workouts does, updates take a
+// merge patch and, like deletes, the version they were made against.
+// Instead of an id, updates and deletes may give the index of an earlier
+// create as "ref". Versions are checked as the earlier operations left the
+// workout, so a batch can change a workout several times. With "atomic" the
+// batch is all or nothing; otherwise every operation stands on its own.
+// Each operation gets a result with the status it would have had on its
+// own, or 424 if it was held back by a failure elsewhere in an atomic
+// batch or refers to a create that failed.
+func (wh *WorkoutHandler) HandleBatchWorkouts(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
 		return
 	}
 
-	if existingWorkout == nil {
-		http.NotFound(w, r)
+	var req struct {
+		Atomic     bool             `json:"atomic"`
+		Operations []batchOperation `json:"operations"`
+	}
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxImportSize)).Decode(&req)
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid request body"})
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > store.MaxBatchSize {
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": fmt.Sprintf("a batch holds 1 to %d operations", store.MaxBatchSize)})
 		return
 	}
 
-	var updateWorkoutRequest struct {
-		Title          *string              `joson:"title"`
-		Description    *string              `joson:"description"`
-		Duration       *int                 `joson:"duration"`
-		CaloriesBurned *int                 `joson:"calories_burned"`
-		Entries        []store.WorkoutEntry `json:"entries"`
+	results := make([]batchResult, len(req.Operations))
+	ops := []store.BatchOp{}
+	indexes := []int{}
+	// positions maps an operation to its op in the batch sent to the store
+	positions := map[int]int{}
+	for i, operation := range req.Operations {
+		results[i] = batchResult{Index: i, Op: operation.Op, ID: operation.ID}
+		op, status, err := prepareBatchOp(operation, user)
+		if err == nil && operation.Ref != nil {
+			status, err = resolveBatchRef(&op, *operation.Ref, i, req.Operations, positions)
+		}
+		if err != nil {
+			results[i].Status = status
+			results[i].Error = err.Error()
+			continue
+		}
+		positions[i] = len(ops)
+		ops = append(ops, op)
+		indexes = append(indexes, i)
 	}
 
-	err = json.NewDecoder(r.Body).Decode(&updateWorkoutRequest)
+	if req.Atomic && len(ops) < len(req.Operations) {
+		for _, i := range indexes {
+			results[i].Status = http.StatusFailedDependency
+			results[i].Error = store.ErrBatchAborted.Error()
+		}
+		utils.WriteJson(w, http.StatusOK, utils.Envelope{"atomic": true, "results": results})
+		return
+	}
+
+	errs, err := wh.workoutStore.ApplyBatch(ops, user.ID, req.Atomic)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		wh.logger.Printf("ERROR: applyBatch: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to apply batch"})
 		return
 	}
-	if updateWorkoutRequest.Title != nil {
-		existingWorkout.Title = *updateWorkoutRequest.Title
+
+	for j, err := range errs {
+		result := &results[indexes[j]]
+		op := ops[j]
+		var patchErr *batchPatchError
+		switch {
+		case err == nil && op.Op == store.BatchCreate:
+			result.Status = http.StatusCreated
+			result.ID = int64(op.Workout.ID)
+		case err == nil && op.Op == store.BatchDelete:
+			result.Status = http.StatusNoContent
+			result.ID = op.ID
+		case err == nil:
+			result.Status = http.StatusOK
+			result.ID = op.ID
+		case errors.As(err, &patchErr):
+			result.Status = patchErr.status
+			if patchErr.status == http.StatusInternalServerError {
+				wh.logger.Printf("ERROR: batch patchWorkout: %v", err)
+				err = errors.New("internal server error")
+			}
+		case errors.Is(err, store.ErrBatchAborted):
+			result.Status = http.StatusFailedDependency
+		case errors.Is(err, sql.ErrNoRows):
+			result.Status = http.StatusNotFound
+		case errors.Is(err, store.ErrVersionConflict):
+			result.Status = http.StatusPreconditionFailed
+		case isInvalidWorkoutError(err):
+			result.Status = http.StatusUnprocessableEntity
+		default:
+			wh.logger.Printf("ERROR: batch %s: %v", op.Op, err)
+			result.Status = http.StatusInternalServerError
+			err = errors.New("internal server error")
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+			continue
+		}
+		if op.Workout != nil {
+			op.Workout.ConvertWeights(user.PreferredWeightUnit())
+			result.Workout = op.Workout
+		}
 	}
-	if updateWorkoutRequest.Description != nil {
-		existingWorkout.Description = *updateWorkoutRequest.Description
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"atomic": req.Atomic, "results": results})
+}
+
+// batchPatchError carries the status of an update whose patch could not
+// be applied to the workout.
+type batchPatchError struct {
+	status int
+	err    error
+}
+
+func (e *batchPatchError) Error() string { return e.err.Error() }
+
+func (e *batchPatchError) Unwrap() error { return e.err }
+
+// prepareBatchOp turns one batch operation into the store operation, or
+// returns the status to report. Ownership and versions are checked by the
+// store, against the workouts as the earlier operations left them.
+func prepareBatchOp(operation batchOperation, user *store.User) (store.BatchOp, int, error) {
+	op := store.BatchOp{Op: operation.Op, ID: operation.ID, Version: operation.Version}
+
+	if operation.Op == store.BatchCreate {
+		if operation.Ref != nil {
+			return op, http.StatusBadRequest, errors.New("creates cannot have a ref")
+		}
+		var workout store.Workout
+		err := json.Unmarshal(operation.Workout, &workout)
+		if err != nil {
+			return op, http.StatusBadRequest, errors.New("workout must be a workout object")
+		}
+		prepareNewWorkout(&workout, user)
+		op.Workout = &workout
+		return op, 0, nil
+	}
+
+	if operation.Op != store.BatchUpdate && operation.Op != store.BatchDelete {
+		return op, http.StatusBadRequest, store.ErrInvalidBatchOp
+	}
+	if operation.Version < 1 {
+		return op, http.StatusPreconditionRequired, errors.New("version is required")
 	}
-	if updateWorkoutRequest.Duration != nil {
-		existingWorkout.Duration = *updateWorkoutRequest.Duration
+
+	if operation.Op == store.BatchUpdate {
+		patch := operation.Workout
+		op.Patch = func(current *store.Workout) (*store.Workout, error) {
+			workout, status, err := patchWorkout(current, patch, jsonpatch.MergePatch, user)
+			if err != nil {
+				return nil, &batchPatchError{status: status, err: err}
+			}
+			return workout, nil
+		}
 	}
-	if updateWorkoutRequest.CaloriesBurned != nil {
-		existingWorkout.CaloriesBurned = *updateWorkoutRequest.CaloriesBurned
+	return op, 0, nil
+}
+
+// resolveBatchRef points op at the create operation ref, which must come
+// before operation i. A create that was rejected before reaching the store
+// holds the op back with 424.
+func resolveBatchRef(op *store.BatchOp, ref, i int, operations []batchOperation, positions map[int]int) (int, error) {
+	if ref < 0 || ref >= i || operations[ref].Op != store.BatchCreate {
+		return http.StatusBadRequest, errors.New("ref must be the index of an earlier create")
 	}
-	if updateWorkoutRequest.Entries != nil {
-		existingWorkout.Entries = updateWorkoutRequest.Entries
+	position, ok := positions[ref]
+	if !ok {
+		return http.StatusFailedDependency, store.ErrBatchAborted
 	}
+	op.Ref = &position
+	return 0, nil
+}
 
+func (wh *WorkoutHandler) HandleDeleteWorkoutById(w http.ResponseWriter, r *http.Request) {
 	user := middleware.GetUser(r)
-	if user == nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
 		return
 	}
 
-	workOutOwner, err := wh.workoutStore.GetWorkoutByID(workoutId)
+	workoutId, err := utils.ReadIDParam(r)
 	if err != nil {
-		http.Error(w, "failed to fetch workout", http.StatusInternalServerError)
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
 		return
 	}
 
-	if workOutOwner.UserID != user.ID {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	workout, err := wh.workoutStore.GetWorkoutByID(workoutId)
+	if err == sql.ErrNoRows || (err == nil && workout.UserID != user.ID) {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: getWorkoutByID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to fetch workout"})
 		return
 	}
 
-	err = wh.workoutStore.UpdateWorkout(existingWorkout)
+	if !checkIfMatch(w, r, workout) {
+		return
+	}
+
+	err = wh.workoutStore.DeleteWorkout(workoutId, workout.Version, user.ID)
+	if err == sql.ErrNoRows {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout not found"})
+		return
+	}
+	if errors.Is(err, store.ErrVersionConflict) {
+		utils.WriteJson(w, http.StatusPreconditionFailed, utils.Envelope{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		http.Error(w, "failed to update workout", http.StatusInternalServerError)
+		wh.logger.Printf("ERROR: deleteWorkout: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to delete workout"})
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(existingWorkout)
+	w.WriteHeader(http.StatusNoContent)
+
 }
 
-func (wh *WorkoutHandler) HandleDeleteWorkoutById(w http.ResponseWriter, r *http.Request) {
-	paramsWorkoutId := chi.URLParam(r, "id")
+// HandleListTrash lists the user's deleted workouts that can still be
+// restored.
+func (wh *WorkoutHandler) HandleListTrash(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
 
-	if paramsWorkoutId == "" {
-		http.NotFound(w, r)
+	workouts, err := wh.workoutStore.ListTrash(int64(user.ID))
+	if err != nil {
+		wh.logger.Printf("ERROR: listTrash: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to list trash"})
 		return
 	}
 
-	workoutId, err := strconv.ParseInt(paramsWorkoutId, 10, 64)
+	for _, workout := range workouts {
+		workout.ConvertWeights(user.PreferredWeightUnit())
+	}
+
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"workouts": workouts, "retention_days": int(store.TrashRetention.Hours() / 24)})
+}
+
+func (wh *WorkoutHandler) HandleRestoreWorkout(w http.ResponseWriter, r *http.Request) {
+	user := middleware.GetUser(r)
+	if user == nil || user.IsAnnoymous() {
+		utils.WriteJson(w, http.StatusUnauthorized, utils.Envelope{"error": "unauthorized"})
+		return
+	}
 
+	workoutID, err := utils.ReadIDParam(r)
 	if err != nil {
-		http.NotFound(w, r)
+		wh.logger.Printf("ERROR: readIDParam: %v", err)
+		utils.WriteJson(w, http.StatusBadRequest, utils.Envelope{"error": "invalid id"})
 		return
 	}
 
-	err = wh.workoutStore.DeleteWorkout(workoutId)
+	err = wh.workoutStore.RestoreWorkout(workoutID, int64(user.ID))
+	if err == sql.ErrNoRows {
+		utils.WriteJson(w, http.StatusNotFound, utils.Envelope{"error": "workout not found in trash"})
+		return
+	}
+	if err != nil {
+		wh.logger.Printf("ERROR: restoreWorkout: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "failed to restore workout"})
+		return
+	}
 
+	workout, err := wh.workoutStore.GetWorkoutByID(workoutID)
 	if err != nil {
-		http.Error(w, "failed to delete workout", http.StatusInternalServerError)
+		wh.logger.Printf("ERROR: getWorkoutByID: %v", err)
+		utils.WriteJson(w, http.StatusInternalServerError, utils.Envelope{"error": "internal server error"})
 		return
 	}
-	w.WriteHeader(http.StatusNoContent)
 
+	workout.ConvertWeights(user.PreferredWeightUnit())
+	utils.WriteJson(w, http.StatusOK, utils.Envelope{"workout": workout})
+}
+
+// workoutVersionTag identifies one version of a workout as shown in unit,
+// since the same version reads differently in kilograms and pounds.
+func workoutVersionTag(workout *store.Workout, unit string) string {
+	return fmt.Sprintf("%d.%d.%s", workout.ID, workout.Version, unit)
+}
+
+// workoutETag is the version tag plus the reaction counts, which change
+// without a new version. It decides whether a cached copy is still fresh.
+func workoutETag(workout *store.Workout, unit string) string {
+	reactions := fnv.New32a()
+	for _, emoji := range store.Reactions {
+		fmt.Fprintf(reactions, "%d,", workout.Reactions[emoji])
+	}
+	return utils.ETag(fmt.Sprintf("%s.%x", workoutVersionTag(workout, unit), reactions.Sum32()))
+}
+
+// checkIfMatch makes writes conditional: the request must carry an
+// If-Match header naming an ETag of the workout's current version. Only
+// the version counts, so reactions added since the client read the
+// workout do not fail its write. It answers 428 when the header is
+// missing and 412 when the workout has moved on.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, workout *store.Workout) bool {
+	match := r.Header.Get("If-Match")
+	if match == "" {
+		utils.WriteJson(w, http.StatusPreconditionRequired, utils.Envelope{"error": "If-Match header is required"})
+		return false
+	}
+
+	unit := store.UnitKilograms
+	if user := middleware.GetUser(r); user != nil {
+		unit = user.PreferredWeightUnit()
+	}
+	if !matchesWorkoutVersion(match, workoutVersionTag(workout, unit)) {
+		w.Header().Set("ETag", workoutETag(workout, unit))
+		utils.WriteJson(w, http.StatusPreconditionFailed, utils.Envelope{"error": store.ErrVersionConflict.Error()})
+		return false
+	}
+	return true
+}
+
+// matchesWorkoutVersion reports whether an If-Match header lists a strong
+// tag of version, with or without the reaction counts workoutETag adds.
+func matchesWorkoutVersion(header, version string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if !strings.HasPrefix(candidate, `"`) {
+			continue
+		}
+		value := strings.Trim(candidate, `"`)
+		if value == version || strings.HasPrefix(value, version+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// readOnlyWorkoutFields are managed by the server and cannot be patched.
+var readOnlyWorkoutFields = []string{
+	"id", "user_id", "version", "created_at", "deleted_at",
+	"calories_estimated", "cardio", "reactions", "new_records",
+}
+
+// prepareNewWorkout assigns a new workout to the user, reading weights
+// without a unit in the user's unit and taking a calories_burned sent by
+// the client as its own figure.
+func prepareNewWorkout(workout *store.Workout, user *store.User) {
+	workout.UserID = user.ID
+	store.SetDefaultWeightUnit(workout.Entries, user.PreferredWeightUnit())
+	if workout.CaloriesReported == nil && workout.CaloriesBurned > 0 {
+		reported := workout.CaloriesBurned
+		workout.CaloriesReported = &reported
+	}
+	workout.EstimateCalories(user.BodyWeight)
+}
+
+// patchWorkout applies patch to the workout as the user sees it, weights in
+// their unit, and returns the validated result ready to be saved. On error
+// it also returns the status code to answer with.
+func patchWorkout(existing *store.Workout, patch []byte, apply func(doc, patch []byte) ([]byte, error), user *store.User) (*store.Workout, int, error) {
+	stored := append([]store.WorkoutEntry(nil), existing.Entries...)
+	existing.ConvertWeights(user.PreferredWeightUnit())
+	original, err := json.Marshal(existing)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	patched, err := apply(original, patch)
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return nil, http.StatusConflict, err
+	}
+	if errors.Is(err, jsonpatch.ErrPathNotFound) {
+		return nil, http.StatusUnprocessableEntity, err
+	}
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	workout, err := decodePatchedWorkout(original, patched)
+	if err != nil {
+		return nil, http.StatusUnprocessableEntity, err
+	}
+
+	// a changed calories_burned is the user's own figure, as on create
+	if workout.CaloriesBurned > 0 && workout.CaloriesBurned != existing.CaloriesBurned &&
+		reflect.DeepEqual(workout.CaloriesReported, existing.CaloriesReported) {
+		reported := workout.CaloriesBurned
+		workout.CaloriesReported = &reported
+	}
+	store.SetDefaultWeightUnit(workout.Entries, user.PreferredWeightUnit())
+	workout.KeepStoredWeights(stored, user.PreferredWeightUnit())
+
+	err = workout.Validate()
+	if err != nil {
+		return nil, http.StatusUnprocessableEntity, err
+	}
+	workout.EstimateCalories(user.BodyWeight)
+	return workout, 0, nil
+}
+
+// patchFunc picks the patch format from the request's Content-Type.
+func patchFunc(contentType string) (func(doc, patch []byte) ([]byte, error), bool) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	switch mediaType {
+	case jsonpatch.MediaTypeMergePatch, "application/json":
+		return jsonpatch.MergePatch, true
+	case jsonpatch.MediaTypeJSONPatch:
+		return jsonpatch.Apply, true
+	}
+	return nil, false
+}
+
+// replaceDocument stands in for a patch on PUT: body becomes the workout,
+// with the read-only fields copied over from doc.
+func replaceDocument(doc, body []byte) ([]byte, error) {
+	var before, after map[string]json.RawMessage
+	err := json.Unmarshal(doc, &before)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(body, &after)
+	if err != nil || after == nil {
+		return nil, errors.New("a workout must be a JSON object")
+	}
+	for _, field := range readOnlyWorkoutFields {
+		if value, ok := before[field]; ok {
+			after[field] = value
+		} else {
+			delete(after, field)
+		}
+	}
+	return json.Marshal(after)
+}
+
+// decodePatchedWorkout turns a patched document back into a workout,
+// rejecting unknown fields, wrong types and changes to read-only fields.
+func decodePatchedWorkout(original, patched []byte) (*store.Workout, error) {
+	var before, after map[string]json.RawMessage
+	err := json.Unmarshal(original, &before)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(patched, &after)
+	if err != nil {
+		return nil, errors.New("a workout must be a JSON object")
+	}
+	for _, field := range readOnlyWorkoutFields {
+		if (before[field] == nil) != (after[field] == nil) ||
+			(before[field] != nil && !jsonpatch.Equal(before[field], after[field])) {
+			return nil, fmt.Errorf("%s is read-only", field)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	var workout store.Workout
+	err = decoder.Decode(&workout)
+	if err != nil {
+		return nil, err
+	}
+	return &workout, nil
+}
+
+// isInvalidWorkoutError reports whether the store rejected a workout because
+// of what the client sent rather than because of a server problem.
+func isInvalidWorkoutError(err error) bool {
+	return errors.Is(err, store.ErrInvalidWorkout) ||
+		errors.Is(err, store.ErrUnknownExercise) ||
+		errors.Is(err, store.ErrInvalidWorkoutSet) ||
+		errors.Is(err, store.ErrInvalidWorkoutEntry) ||
+		errors.Is(err, store.ErrInvalidVisibility) ||
+		errors.Is(err, store.ErrInvalidTag) ||
+		errors.Is(err, store.ErrInvalidWeightUnit)
 }
@@ -0,0 +1,389 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mhdph/go-start/internal/middleware"
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWorkoutStore keeps workouts in memory. Methods the tests do not use
+// panic through the nil embedded interface.
+type fakeWorkoutStore struct {
+	store.WorkoutStore
+	workouts map[int64]*store.Workout
+	nextID   int
+}
+
+func newFakeWorkoutStore(workouts ...*store.Workout) *fakeWorkoutStore {
+	fake := &fakeWorkoutStore{workouts: map[int64]*store.Workout{}, nextID: 100}
+	for _, workout := range workouts {
+		fake.workouts[int64(workout.ID)] = workout
+	}
+	return fake
+}
+
+func (f *fakeWorkoutStore) GetWorkoutByID(id int64) (*store.Workout, error) {
+	workout, ok := f.workouts[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	stored := *workout
+	return &stored, nil
+}
+
+func (f *fakeWorkoutStore) DeleteWorkout(id int64, version int, actorID int) error {
+	workout, ok := f.workouts[id]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if workout.Version != version {
+		return store.ErrVersionConflict
+	}
+	delete(f.workouts, id)
+	return nil
+}
+
+func (f *fakeWorkoutStore) UpdateWorkout(workout *store.Workout, actorID int) error {
+	stored, ok := f.workouts[int64(workout.ID)]
+	if !ok {
+		return sql.ErrNoRows
+	}
+	if stored.Version != workout.Version {
+		return store.ErrVersionConflict
+	}
+	workout.Version++
+	saved := *workout
+	f.workouts[int64(workout.ID)] = &saved
+	return nil
+}
+
+// ApplyBatch follows the store: every op sees the workouts as the ops
+// before it left them, and a failed atomic batch leaves nothing behind.
+func (f *fakeWorkoutStore) ApplyBatch(ops []store.BatchOp, actorID int, atomic bool) ([]error, error) {
+	before := make(map[int64]*store.Workout, len(f.workouts))
+	for id, workout := range f.workouts {
+		before[id] = workout
+	}
+
+	results := make([]error, len(ops))
+	failed := false
+	for i := range ops {
+		if atomic && failed {
+			results[i] = store.ErrBatchAborted
+			continue
+		}
+		results[i] = f.applyBatchOp(ops, i, results, actorID)
+		failed = failed || results[i] != nil
+	}
+
+	if atomic && failed {
+		f.workouts = before
+		for i := range results {
+			if results[i] == nil {
+				results[i] = store.ErrBatchAborted
+			}
+		}
+	}
+	return results, nil
+}
+
+func (f *fakeWorkoutStore) applyBatchOp(ops []store.BatchOp, i int, results []error, actorID int) error {
+	op := &ops[i]
+	if op.Ref != nil {
+		if results[*op.Ref] != nil {
+			return store.ErrBatchAborted
+		}
+		op.ID = int64(ops[*op.Ref].Workout.ID)
+	}
+
+	if op.Op == store.BatchCreate {
+		f.nextID++
+		op.Workout.ID = f.nextID
+		op.Workout.Version = 1
+		saved := *op.Workout
+		f.workouts[int64(saved.ID)] = &saved
+		return nil
+	}
+
+	current, err := f.GetWorkoutByID(op.ID)
+	if err != nil {
+		return err
+	}
+	if current.UserID != actorID {
+		return sql.ErrNoRows
+	}
+	if current.Version != op.Version {
+		return store.ErrVersionConflict
+	}
+	if op.Op == store.BatchDelete {
+		delete(f.workouts, op.ID)
+		return nil
+	}
+
+	workout, err := op.Patch(current)
+	if err != nil {
+		return err
+	}
+	workout.Version++
+	saved := *workout
+	f.workouts[op.ID] = &saved
+	op.Workout = workout
+	return nil
+}
+
+func newTestRouter(wh *WorkoutHandler, user *store.User) http.Handler {
+	r := chi.NewRouter()
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, middleware.SetUser(r, user))
+		})
+	})
+	r.Get("/workouts/{id}", wh.HandleGetWorkoutByID)
+	r.Put("/workouts/{id}", wh.HandleReplaceWorkoutById)
+	r.Patch("/workouts/{id}", wh.HandleUpdateWorkoutById)
+	r.Delete("/workouts/{id}", wh.HandleDeleteWorkoutById)
+	r.Post("/workouts:batch", wh.HandleBatchWorkouts)
+	return r
+}
+
+func serve(handler http.Handler, method, target string, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for name, value := range header {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWorkoutConditionalRequests(t *testing.T) {
+	owner := &store.User{ID: 1}
+	workouts := newFakeWorkoutStore(&store.Workout{ID: 7, UserID: 1, Title: "Push day", Visibility: store.VisibilityPrivate, Version: 3})
+	handler := newTestRouter(NewWorkoutHandler(workouts, nil, log.New(io.Discard, "", 0)), owner)
+
+	rec := serve(handler, http.MethodGet, "/workouts/7", "", nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	t.Run("a fresh copy is not modified", func(t *testing.T) {
+		rec := serve(handler, http.MethodGet, "/workouts/7", "", map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+
+		rec = serve(handler, http.MethodGet, "/workouts/7", "", map[string]string{"If-None-Match": "W/" + etag})
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+	})
+
+	t.Run("reactions change the etag", func(t *testing.T) {
+		workouts.workouts[7].Reactions = map[string]int{store.Reactions[0]: 1}
+		defer func() { workouts.workouts[7].Reactions = nil }()
+
+		rec := serve(handler, http.MethodGet, "/workouts/7", "", map[string]string{"If-None-Match": etag})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	})
+
+	t.Run("writes need If-Match", func(t *testing.T) {
+		rec := serve(handler, http.MethodDelete, "/workouts/7", "", nil)
+		assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+		assert.JSONEq(t, `{"error":"If-Match header is required"}`, rec.Body.String())
+
+		rec = serve(handler, http.MethodPatch, "/workouts/7", `{"title":"Pull day"}`, map[string]string{"Content-Type": "application/merge-patch+json"})
+		assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+	})
+
+	t.Run("a stale or weak If-Match fails", func(t *testing.T) {
+		rec := serve(handler, http.MethodDelete, "/workouts/7", "", map[string]string{"If-Match": `"7.2.kg"`})
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		assert.Equal(t, etag, rec.Header().Get("ETag"))
+
+		rec = serve(handler, http.MethodDelete, "/workouts/7", "", map[string]string{"If-Match": "W/" + etag})
+		assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"error"`)
+	})
+
+	t.Run("reactions do not fail a write", func(t *testing.T) {
+		workouts.workouts[7].Reactions = map[string]int{store.Reactions[0]: 1}
+		defer func() { workouts.workouts[7].Reactions = nil }()
+
+		rec := serve(handler, http.MethodPatch, "/workouts/7", `{"title":"Pull day"}`, map[string]string{
+			"Content-Type": "application/merge-patch+json",
+			"If-Match":     etag,
+		})
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		etag = rec.Header().Get("ETag")
+	})
+
+	t.Run("other users cannot delete", func(t *testing.T) {
+		other := newTestRouter(NewWorkoutHandler(workouts, nil, log.New(io.Discard, "", 0)), &store.User{ID: 2})
+		rec := serve(other, http.MethodDelete, "/workouts/7", "", map[string]string{"If-Match": etag})
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, workouts.workouts, int64(7))
+	})
+
+	rec = serve(handler, http.MethodDelete, "/workouts/7", "", map[string]string{"If-Match": etag})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.NotContains(t, workouts.workouts, int64(7))
+
+	rec = serve(handler, http.MethodGet, "/workouts/7", "", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestReplaceWorkout(t *testing.T) {
+	owner := &store.User{ID: 1}
+	workouts := newFakeWorkoutStore(&store.Workout{ID: 7, UserID: 1, Title: "Push day", Description: "Felt good", Duration: 60, Visibility: store.VisibilityPrivate, Version: 3})
+	handler := newTestRouter(NewWorkoutHandler(workouts, nil, log.New(io.Discard, "", 0)), owner)
+	etag := serve(handler, http.MethodGet, "/workouts/7", "", nil).Header().Get("ETag")
+
+	body := `{"title":"Pull day","duration":45,"version":99,"user_id":2}`
+	rec := serve(handler, http.MethodPut, "/workouts/7", body, nil)
+	assert.Equal(t, http.StatusPreconditionRequired, rec.Code)
+
+	rec = serve(handler, http.MethodPut, "/workouts/7", body, map[string]string{"If-Match": etag})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	saved := workouts.workouts[7]
+	assert.Equal(t, "Pull day", saved.Title)
+	assert.Equal(t, 45, saved.Duration)
+	// fields left out are cleared, the ones the server manages are kept
+	assert.Empty(t, saved.Description)
+	assert.Equal(t, store.VisibilityPrivate, saved.Visibility)
+	assert.Equal(t, 1, saved.UserID)
+	assert.Equal(t, 4, saved.Version)
+	assert.Nil(t, saved.CaloriesReported)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+
+	rec = serve(handler, http.MethodPut, "/workouts/7", `["not a workout"]`, map[string]string{"If-Match": rec.Header().Get("ETag")})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func batchStatuses(t *testing.T, rec *httptest.ResponseRecorder) []int {
+	t.Helper()
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var body struct {
+		Results []batchResult `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	statuses := make([]int, len(body.Results))
+	for i, result := range body.Results {
+		statuses[i] = result.Status
+	}
+	return statuses
+}
+
+func TestBatchWorkouts(t *testing.T) {
+	owner := &store.User{ID: 1}
+	newHandler := func() (http.Handler, *fakeWorkoutStore) {
+		workouts := newFakeWorkoutStore(
+			&store.Workout{ID: 7, UserID: 1, Title: "Push day", Visibility: store.VisibilityPrivate, Version: 3},
+			&store.Workout{ID: 8, UserID: 2, Title: "Not mine", Visibility: store.VisibilityPublic, Version: 1},
+		)
+		return newTestRouter(NewWorkoutHandler(workouts, nil, log.New(io.Discard, "", 0)), owner), workouts
+	}
+
+	t.Run("best effort applies what it can", func(t *testing.T) {
+		handler, workouts := newHandler()
+		body := `{"operations": [
+			{"op": "create", "workout": {"title": "Legs"}},
+			{"op": "update", "ref": 0, "version": 1, "workout": {"title": "Leg day"}},
+			{"op": "update", "id": 7, "version": 3, "workout": {"title": "Pull day"}},
+			{"op": "update", "id": 7, "version": 4, "workout": {"duration": 50}},
+			{"op": "update", "id": 7, "version": 3, "workout": {"title": "Stale"}},
+			{"op": "delete", "id": 8, "version": 1},
+			{"op": "update", "id": 7, "version": 5, "workout": {"id": 9}},
+			{"op": "upsert", "id": 7, "version": 5}
+		]}`
+		statuses := batchStatuses(t, serve(handler, http.MethodPost, "/workouts:batch", body, nil))
+		assert.Equal(t, []int{201, 200, 200, 200, 412, 404, 422, 400}, statuses)
+
+		assert.Equal(t, "Leg day", workouts.workouts[101].Title)
+		assert.Equal(t, 2, workouts.workouts[101].Version)
+		assert.Equal(t, "Pull day", workouts.workouts[7].Title)
+		assert.Equal(t, 50, workouts.workouts[7].Duration)
+		assert.Equal(t, 5, workouts.workouts[7].Version)
+		assert.Contains(t, workouts.workouts, int64(8))
+	})
+
+	t.Run("a ref to a failed create is held back", func(t *testing.T) {
+		handler, _ := newHandler()
+		body := `{"operations": [
+			{"op": "create", "workout": ["not a workout"]},
+			{"op": "delete", "ref": 0, "version": 1},
+			{"op": "delete", "ref": 5, "version": 1}
+		]}`
+		statuses := batchStatuses(t, serve(handler, http.MethodPost, "/workouts:batch", body, nil))
+		assert.Equal(t, []int{400, 424, 400}, statuses)
+	})
+
+	t.Run("atomic batches roll back on a failure", func(t *testing.T) {
+		handler, workouts := newHandler()
+		body := `{"atomic": true, "operations": [
+			{"op": "update", "id": 7, "version": 3, "workout": {"title": "Pull day"}},
+			{"op": "update", "id": 7, "version": 4, "workout": {"duration": 50}},
+			{"op": "delete", "id": 7, "version": 4},
+			{"op": "create", "workout": {"title": "Legs"}}
+		]}`
+		statuses := batchStatuses(t, serve(handler, http.MethodPost, "/workouts:batch", body, nil))
+		assert.Equal(t, []int{424, 424, 412, 424}, statuses)
+
+		assert.Equal(t, "Push day", workouts.workouts[7].Title)
+		assert.Equal(t, 3, workouts.workouts[7].Version)
+		assert.Len(t, workouts.workouts, 2)
+	})
+
+	t.Run("atomic batches check every operation first", func(t *testing.T) {
+		handler, workouts := newHandler()
+		body := `{"atomic": true, "operations": [
+			{"op": "update", "id": 7, "version": 3, "workout": {"title": "Pull day"}},
+			{"op": "delete", "id": 7}
+		]}`
+		statuses := batchStatuses(t, serve(handler, http.MethodPost, "/workouts:batch", body, nil))
+		assert.Equal(t, []int{424, 428}, statuses)
+		assert.Equal(t, 3, workouts.workouts[7].Version)
+	})
+
+	t.Run("atomic batches commit a chain of edits", func(t *testing.T) {
+		handler, workouts := newHandler()
+		body := `{"atomic": true, "operations": [
+			{"op": "create", "workout": {"title": "Legs"}},
+			{"op": "update", "ref": 0, "version": 1, "workout": {"title": "Leg day"}},
+			{"op": "update", "id": 7, "version": 3, "workout": {"title": "Pull day"}},
+			{"op": "delete", "id": 7, "version": 4}
+		]}`
+		statuses := batchStatuses(t, serve(handler, http.MethodPost, "/workouts:batch", body, nil))
+		assert.Equal(t, []int{201, 200, 200, 204}, statuses)
+		assert.Equal(t, "Leg day", workouts.workouts[101].Title)
+		assert.NotContains(t, workouts.workouts, int64(7))
+	})
+}
+
+func TestMatchesWorkoutVersion(t *testing.T) {
+	tests := []struct {
+		header string
+		want   bool
+	}{
+		{header: `"7.3.kg.1a2b3c4d"`, want: true},
+		{header: `"7.3.kg"`, want: true},
+		{header: `"1.1.kg", "7.3.kg.0"`, want: true},
+		{header: "*", want: true},
+		{header: `W/"7.3.kg.1a2b3c4d"`, want: false},
+		{header: `"7.2.kg.1a2b3c4d"`, want: false},
+		{header: `"7.3.lb.1a2b3c4d"`, want: false},
+		{header: `"7.31.kg"`, want: false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.want, matchesWorkoutVersion(test.header, "7.3.kg"), test.header)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/mhdph/go-start/internal/api"
 	"github.com/mhdph/go-start/internal/middleware"
@@ -14,12 +15,25 @@ import (
 )
 
 type Application struct {
-	Logger         *log.Logger
-	WorkoutHandler *api.WorkoutHandler
-	UserHandler    *api.UserHandler
-	TokenHandler   *api.TokenHandler
-	Middleware     middleware.UserMiddlware
-	DB             *sql.DB
+	Logger           *log.Logger
+	WorkoutStore     store.WorkoutStore
+	WorkoutHandler   *api.WorkoutHandler
+	UserHandler      *api.UserHandler
+	TokenHandler     *api.TokenHandler
+	TemplateHandler  *api.TemplateHandler
+	ProgramHandler   *api.ProgramHandler
+	RecordHandler    *api.RecordHandler
+	ExerciseHandler  *api.ExerciseHandler
+	AnalyticsHandler *api.AnalyticsHandler
+	ShareHandler     *api.ShareHandler
+	SocialHandler    *api.SocialHandler
+	CommentHandler   *api.CommentHandler
+	TagHandler       *api.TagHandler
+	RevisionHandler  *api.RevisionHandler
+	EntryHandler     *api.EntryHandler
+	GoalHandler      *api.GoalHandler
+	Middleware       middleware.UserMiddlware
+	DB               *sql.DB
 }
 
 func NewApplication() (*Application, error) {
@@ -39,24 +53,79 @@ func NewApplication() (*Application, error) {
 	workoutStore := store.NewPostgresWorkoutStore(pgDb)
 	userStore := store.NewPostgresUserStore(pgDb)
 	tokenStore := store.NewPostgresTokenStore(pgDb)
+	templateStore := store.NewPostgresTemplateStore(pgDb)
+	programStore := store.NewPostgresProgramStore(pgDb)
+	recordStore := store.NewPostgresRecordStore(pgDb)
+	exerciseStore := store.NewPostgresExerciseStore(pgDb)
+	analyticsStore := store.NewPostgresAnalyticsStore(pgDb)
+	shareStore := store.NewPostgresShareStore(pgDb)
+	socialStore := store.NewPostgresSocialStore(pgDb)
+	commentStore := store.NewPostgresCommentStore(pgDb)
+	tagStore := store.NewPostgresTagStore(pgDb)
+	revisionStore := store.NewPostgresRevisionStore(pgDb)
+	entryStore := store.NewPostgresEntryStore(pgDb)
+	goalStore := store.NewPostgresGoalStore(pgDb)
 	userMiddleware := middleware.UserMiddlware{
 		UserStore: userStore,
 	}
-	workoutHandler := api.NewWorkoutHandler(workoutStore, logger)
+	workoutHandler := api.NewWorkoutHandler(workoutStore, socialStore, logger)
 	userHandler := api.NewUserHandler(userStore, logger)
 	tokenHandler := api.NewTokenHandler(tokenStore, userStore, logger)
+	templateHandler := api.NewTemplateHandler(templateStore, workoutStore, logger)
+	programHandler := api.NewProgramHandler(programStore, templateStore, logger)
+	recordHandler := api.NewRecordHandler(recordStore, logger)
+	exerciseHandler := api.NewExerciseHandler(exerciseStore, logger)
+	analyticsHandler := api.NewAnalyticsHandler(analyticsStore, logger)
+	shareHandler := api.NewShareHandler(shareStore, workoutStore, logger)
+	socialHandler := api.NewSocialHandler(socialStore, logger)
+	commentHandler := api.NewCommentHandler(commentStore, socialStore, workoutStore, logger)
+	tagHandler := api.NewTagHandler(tagStore, logger)
+	revisionHandler := api.NewRevisionHandler(revisionStore, workoutStore, logger)
+	entryHandler := api.NewEntryHandler(entryStore, workoutStore, logger)
+	goalHandler := api.NewGoalHandler(goalStore, logger)
 	app := &Application{
-		Logger:         logger,
-		WorkoutHandler: workoutHandler,
-		UserHandler:    userHandler,
-		TokenHandler:   tokenHandler,
-		Middleware:     userMiddleware,
-		DB:             pgDb,
+		Logger:           logger,
+		WorkoutStore:     workoutStore,
+		WorkoutHandler:   workoutHandler,
+		UserHandler:      userHandler,
+		TokenHandler:     tokenHandler,
+		TemplateHandler:  templateHandler,
+		ProgramHandler:   programHandler,
+		RecordHandler:    recordHandler,
+		ExerciseHandler:  exerciseHandler,
+		AnalyticsHandler: analyticsHandler,
+		ShareHandler:     shareHandler,
+		SocialHandler:    socialHandler,
+		CommentHandler:   commentHandler,
+		TagHandler:       tagHandler,
+		RevisionHandler:  revisionHandler,
+		EntryHandler:     entryHandler,
+		GoalHandler:      goalHandler,
+		Middleware:       userMiddleware,
+		DB:               pgDb,
 	}
 
 	return app, nil
 }
 
+// PurgeTrash permanently removes workouts that have been in the trash
+// longer than store.TrashRetention, checking once per interval. It runs
+// until the process exits.
+func (a *Application) PurgeTrash(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := a.WorkoutStore.PurgeTrash(time.Now().Add(-store.TrashRetention))
+		if err != nil {
+			a.Logger.Printf("ERROR: purgeTrash: %v", err)
+		} else if purged > 0 {
+			a.Logger.Printf("purged %d workouts from the trash", purged)
+		}
+		<-ticker.C
+	}
+}
+
 func (a *Application) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, "Status is available\n")
 }
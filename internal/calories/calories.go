@@ -0,0 +1,120 @@
+// Package calories estimates energy expenditure from MET values, the ratio
+// of an activity's metabolic rate to the resting rate. Values follow the
+// Compendium of Physical Activities.
+package calories
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	// DefaultBodyWeight is used when the user has not told us their weight.
+	DefaultBodyWeight = 70.0
+
+	// StrengthMET is moderate effort resistance training, used for any
+	// exercise that is not recognised as something else.
+	StrengthMET = 5.0
+)
+
+// activities are matched against the words of exercise names in order, so
+// more specific keywords come before more general ones. Keywords match
+// whole words only, which keeps "run" out of "Crunch" and "spin" out of
+// "Spinal twist".
+var activities = []struct {
+	keywords []string
+	met      float64
+}{
+	{[]string{"jump rope", "skipping"}, 11.8},
+	{[]string{"sprint", "sprints", "sprinting"}, 11.0},
+	{[]string{"run", "runs", "running"}, 9.8},
+	{[]string{"jog", "jogging"}, 7.0},
+	{[]string{"swim", "swimming"}, 8.0},
+	{[]string{"burpee", "burpees"}, 8.0},
+	{[]string{"hiit"}, 8.0},
+	{[]string{"interval", "intervals"}, 8.0},
+	{[]string{"circuit", "circuits"}, 8.0},
+	{[]string{"stair", "stairs", "stairmaster"}, 9.0},
+	{[]string{"row erg", "rowing"}, 7.0},
+	{[]string{"cycle", "cycling", "bike", "biking"}, 7.5},
+	{[]string{"spin", "spinning"}, 8.5},
+	{[]string{"elliptical"}, 5.0},
+	{[]string{"hike", "hiking"}, 6.0},
+	{[]string{"walk", "walking"}, 3.5},
+	{[]string{"plank", "planks"}, 3.8},
+	{[]string{"crunch", "crunches"}, 3.8},
+	{[]string{"sit-up", "sit-ups", "situp", "situps", "sit up", "sit ups"}, 3.8},
+	{[]string{"yoga"}, 2.5},
+	{[]string{"stretch", "stretches", "stretching"}, 2.3},
+	{[]string{"mobility"}, 2.3},
+}
+
+// Segment is a stretch of a workout spent on one exercise.
+type Segment struct {
+	Exercise string
+	Minutes  float64
+}
+
+// METFor returns the MET value of an exercise by name.
+func METFor(exercise string) float64 {
+	words := strings.FieldsFunc(strings.ToLower(exercise), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+	name := " " + strings.Join(words, " ") + " "
+	for _, activity := range activities {
+		for _, keyword := range activity.keywords {
+			if strings.Contains(name, " "+keyword+" ") {
+				return activity.met
+			}
+		}
+	}
+	return StrengthMET
+}
+
+// Estimate returns the kilocalories burned doing an activity of the given
+// MET for the given minutes.
+func Estimate(met, bodyWeightKg, minutes float64) float64 {
+	if bodyWeightKg <= 0 {
+		bodyWeightKg = DefaultBodyWeight
+	}
+	if met <= 0 || minutes <= 0 {
+		return 0
+	}
+	return met * 3.5 * bodyWeightKg / 200 * minutes
+}
+
+// EstimateWorkout estimates a whole workout lasting totalMinutes. Segments
+// with a known length count for that long; the rest of the workout is
+// shared evenly between the segments without one. A workout without any
+// segments is treated as general strength training.
+func EstimateWorkout(segments []Segment, bodyWeightKg, totalMinutes float64) int {
+	if len(segments) == 0 {
+		return int(math.Round(Estimate(StrengthMET, bodyWeightKg, totalMinutes)))
+	}
+
+	timed := 0.0
+	untimed := 0
+	for _, segment := range segments {
+		if segment.Minutes > 0 {
+			timed += segment.Minutes
+		} else {
+			untimed++
+		}
+	}
+
+	share := 0.0
+	if untimed > 0 && totalMinutes > timed {
+		share = (totalMinutes - timed) / float64(untimed)
+	}
+
+	total := 0.0
+	for _, segment := range segments {
+		minutes := segment.Minutes
+		if minutes <= 0 {
+			minutes = share
+		}
+		total += Estimate(METFor(segment.Exercise), bodyWeightKg, minutes)
+	}
+	return int(math.Round(total))
+}
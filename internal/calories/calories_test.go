@@ -0,0 +1,43 @@
+package calories
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMETFor(t *testing.T) {
+	assert.Equal(t, 9.8, METFor("Morning Run"))
+	assert.Equal(t, 11.8, METFor("jump rope"))
+	assert.Equal(t, 3.8, METFor("Plank"))
+	assert.Equal(t, StrengthMET, METFor("Bench press"))
+
+	// keywords only match whole words
+	assert.Equal(t, 3.8, METFor("Crunch"))
+	assert.Equal(t, 3.8, METFor("Bicycle crunches"))
+	assert.Equal(t, StrengthMET, METFor("Spinal twist"))
+	assert.Equal(t, StrengthMET, METFor("Barbell row"))
+	assert.Equal(t, 8.5, METFor("Spin class"))
+	assert.Equal(t, 9.8, METFor("Running (treadmill)"))
+	assert.Equal(t, 3.8, METFor("Sit-ups"))
+}
+
+func TestEstimateWorkout(t *testing.T) {
+	// 30 minutes of running at 80kg: 9.8 * 3.5 * 80 / 200 * 30
+	assert.InDelta(t, 411.6, Estimate(9.8, 80, 30), 0.001)
+	assert.Equal(t, 0.0, Estimate(9.8, 80, 0))
+
+	// unknown body weight falls back to the default
+	assert.Equal(t, Estimate(StrengthMET, DefaultBodyWeight, 60), Estimate(StrengthMET, 0, 60))
+
+	segments := []Segment{
+		{Exercise: "Treadmill run", Minutes: 20},
+		{Exercise: "Squat"},
+		{Exercise: "Bench press"},
+	}
+	// 20 minutes of running, then 20 minutes each of strength work
+	want := Estimate(9.8, 80, 20) + 2*Estimate(StrengthMET, 80, 20)
+	assert.Equal(t, int(want+0.5), EstimateWorkout(segments, 80, 60))
+
+	assert.Equal(t, 368, EstimateWorkout(nil, 70, 60))
+}
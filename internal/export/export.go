@@ -0,0 +1,281 @@
+// Package export writes workouts out one at a time in formats other tools
+// understand, so a full history never has to be held in memory.
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mhdph/go-start/internal/store"
+)
+
+const (
+	FormatCSV   = "csv"
+	FormatJSONL = "jsonl"
+	FormatICS   = "ics"
+)
+
+var ErrUnknownFormat = errors.New("format must be csv, jsonl or ics")
+
+// Writer streams workouts. Close finishes the document but does not close
+// the underlying io.Writer.
+type Writer interface {
+	Write(workout *store.Workout) error
+	Close() error
+}
+
+func ValidFormat(format string) bool {
+	return format == FormatCSV || format == FormatJSONL || format == FormatICS
+}
+
+// NewWriter returns the writer for format. CSV and iCalendar writers start
+// writing their header straight away.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatJSONL:
+		return &jsonlWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatICS:
+		return newICSWriter(w)
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// ContentType is the media type of format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson"
+	case FormatICS:
+		return "text/calendar; charset=utf-8"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+var csvHeader = []string{
+	"workout_id", "date", "title", "description", "duration_minutes", "calories_burned",
+	"order_index", "exercise_id", "exercise_name", "entry_type", "sets", "reps", "weight", "weight_unit", "entry_duration_seconds",
+	"distance_meters", "group_label", "group_type", "group_rounds", "group_rest_seconds", "notes",
+}
+
+type csvWriter struct {
+	csv *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := &csvWriter{csv: csv.NewWriter(w)}
+	return cw, cw.csv.Write(csvHeader)
+}
+
+// Write emits one row per entry, or a single row with empty entry columns
+// for a workout without entries.
+func (cw *csvWriter) Write(workout *store.Workout) error {
+	prefix := []string{
+		strconv.Itoa(workout.ID),
+		workout.CreatedAt.UTC().Format(time.RFC3339),
+		workout.Title,
+		workout.Description,
+		strconv.Itoa(workout.Duration),
+		strconv.Itoa(workout.CaloriesBurned),
+	}
+
+	if len(workout.Entries) == 0 {
+		return cw.csv.Write(append(prefix, make([]string, len(csvHeader)-len(prefix))...))
+	}
+
+	for _, entry := range workout.Entries {
+		var group [4]string
+		if entry.Group != nil {
+			group = [4]string{entry.Group.Label, entry.Group.Type, strconv.Itoa(entry.Group.Rounds), formatIntPtr(entry.Group.RestSeconds)}
+		}
+
+		row := append([]string{}, prefix...)
+		row = append(row,
+			strconv.Itoa(entry.OrderIndex),
+			formatIntPtr(entry.ExerciseID),
+			entry.ExerciesName,
+			entry.Type,
+			strconv.Itoa(entry.Sets),
+			formatIntPtr(entry.Reps),
+			formatFloatPtr(entry.Weight),
+			entry.WeightUnit,
+			formatIntPtr(entry.Duration),
+			formatFloatPtr(entry.Distance),
+			group[0], group[1], group[2], group[3],
+			entry.Notes,
+		)
+		err := cw.csv.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.csv.Flush()
+	return cw.csv.Error()
+}
+
+func (cw *csvWriter) Close() error {
+	cw.csv.Flush()
+	return cw.csv.Error()
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func (jw *jsonlWriter) Write(workout *store.Workout) error {
+	return jw.encoder.Encode(workout)
+}
+
+func (jw *jsonlWriter) Close() error {
+	return nil
+}
+
+// icsWriter writes an RFC 5545 calendar with one event per workout.
+type icsWriter struct {
+	w     io.Writer
+	stamp string
+}
+
+func newICSWriter(w io.Writer) (*icsWriter, error) {
+	iw := &icsWriter{w: w, stamp: time.Now().UTC().Format("20060102T150405Z")}
+	err := iw.lines(
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//go-start//workouts//EN",
+		"CALSCALE:GREGORIAN",
+	)
+	return iw, err
+}
+
+func (iw *icsWriter) Write(workout *store.Workout) error {
+	description := []string{}
+	if workout.Description != "" {
+		description = append(description, workout.Description)
+	}
+	description = append(description, describeEntries(workout.Entries)...)
+
+	return iw.lines(
+		"BEGIN:VEVENT",
+		fmt.Sprintf("UID:workout-%d@go-start", workout.ID),
+		"DTSTAMP:"+iw.stamp,
+		"DTSTART:"+workout.CreatedAt.UTC().Format("20060102T150405Z"),
+		fmt.Sprintf("DURATION:PT%dM", workout.Duration),
+		"SUMMARY:"+escapeICSText(workout.Title),
+		"DESCRIPTION:"+escapeICSText(strings.Join(description, "\n")),
+		"END:VEVENT",
+	)
+}
+
+func (iw *icsWriter) Close() error {
+	return iw.lines("END:VCALENDAR")
+}
+
+func (iw *icsWriter) lines(lines ...string) error {
+	for _, line := range lines {
+		_, err := io.WriteString(iw.w, foldICSLine(line)+"\r\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// describeEntries gives one line per entry, folding the entries of a
+// superset or circuit into a single line such as
+// "Circuit A, 3 rounds, 60s rest: Burpees 1x10 + Pull-ups 1x5".
+func describeEntries(entries []store.WorkoutEntry) []string {
+	lines := []string{}
+	for i := 0; i < len(entries); i++ {
+		group := entries[i].Group
+		if group == nil {
+			lines = append(lines, describeEntry(entries[i]))
+			continue
+		}
+
+		parts := []string{}
+		for ; i < len(entries) && entries[i].Group != nil && entries[i].Group.Label == group.Label; i++ {
+			parts = append(parts, describeEntry(entries[i]))
+		}
+		i--
+
+		header := fmt.Sprintf("%s %s, %d rounds", strings.ToUpper(group.Type[:1])+group.Type[1:], group.Label, group.Rounds)
+		if group.Rounds == 1 {
+			header = fmt.Sprintf("%s %s", strings.ToUpper(group.Type[:1])+group.Type[1:], group.Label)
+		}
+		if group.RestSeconds != nil {
+			header += fmt.Sprintf(", %ds rest", *group.RestSeconds)
+		}
+		lines = append(lines, header+": "+strings.Join(parts, " + "))
+	}
+	return lines
+}
+
+func describeEntry(entry store.WorkoutEntry) string {
+	var b strings.Builder
+	b.WriteString(entry.ExerciesName)
+	if entry.Sets > 0 && entry.Reps != nil {
+		fmt.Fprintf(&b, " %dx%d", entry.Sets, *entry.Reps)
+	}
+	if entry.Weight != nil {
+		fmt.Fprintf(&b, " @ %s%s", strconv.FormatFloat(*entry.Weight, 'f', -1, 64), entry.WeightUnit)
+	}
+	if entry.Distance != nil {
+		fmt.Fprintf(&b, " %sm", strconv.FormatFloat(*entry.Distance, 'f', -1, 64))
+	}
+	if entry.Duration != nil {
+		fmt.Fprintf(&b, " %ds", *entry.Duration)
+	}
+	return b.String()
+}
+
+func escapeICSText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine splits content lines longer than 75 octets, continuing them
+// on lines that start with a space, without breaking UTF-8 sequences.
+func foldICSLine(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
+
+func formatIntPtr(i *int) string {
+	if i == nil {
+		return ""
+	}
+	return strconv.Itoa(*i)
+}
+
+func formatFloatPtr(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*f, 'f', -1, 64)
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testWorkout() *store.Workout {
+	reps, weight := 5, 100.0
+	return &store.Workout{
+		ID:          42,
+		Title:       "Push day, heavy",
+		Description: "felt strong",
+		Duration:    60,
+		CreatedAt:   time.Date(2025, 3, 1, 18, 30, 0, 0, time.UTC),
+		Entries: []store.WorkoutEntry{
+			{ExerciesName: "Bench press", Type: store.EntryTypeStrength, Sets: 3, Reps: &reps, Weight: &weight, WeightUnit: store.UnitKilograms},
+		},
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatCSV, &buf)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Write(testWorkout()))
+	require.NoError(t, w.Write(&store.Workout{ID: 43, Title: "Rest"}))
+	require.NoError(t, w.Close())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "workout_id,date,title"))
+	assert.Equal(t, `42,2025-03-01T18:30:00Z,"Push day, heavy",felt strong,60,0,0,,Bench press,strength,3,5,100,kg,,,,,,,`, lines[1])
+	assert.Equal(t, strings.Count(lines[0], ","), strings.Count(lines[2], ","))
+}
+
+func TestICSWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatICS, &buf)
+	require.NoError(t, err)
+
+	require.NoError(t, w.Write(testWorkout()))
+	require.NoError(t, w.Close())
+
+	out := buf.String()
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+	assert.Contains(t, out, "DTSTART:20250301T183000Z\r\n")
+	assert.Contains(t, out, "DURATION:PT60M\r\n")
+	assert.Contains(t, out, `SUMMARY:Push day\, heavy`)
+	assert.Contains(t, out, `DESCRIPTION:felt strong\nBench press 3x5 @ 100kg`)
+}
+
+func TestICSWriterGroups(t *testing.T) {
+	reps, rest := 10, 60
+	workout := testWorkout()
+	circuit := &store.EntryGroup{Label: "A", Type: store.GroupTypeCircuit, Rounds: 3, RestSeconds: &rest}
+	workout.Entries = append(workout.Entries,
+		store.WorkoutEntry{ExerciesName: "Burpees", Sets: 1, Reps: &reps, Group: circuit},
+		store.WorkoutEntry{ExerciesName: "Pull-ups", Sets: 1, Reps: &reps, Group: circuit},
+	)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatICS, &buf)
+	require.NoError(t, err)
+	require.NoError(t, w.Write(workout))
+	require.NoError(t, w.Close())
+
+	unfolded := strings.ReplaceAll(buf.String(), "\r\n ", "")
+	assert.Contains(t, unfolded, `\nCircuit A\, 3 rounds\, 60s rest: Burpees 1x10 + Pull-ups 1x10`+"\r\n")
+}
+
+func TestFoldICSLine(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("é", 60)
+	folded := foldICSLine(line)
+	for _, part := range strings.Split(folded, "\r\n") {
+		assert.LessOrEqual(t, len(part), 75)
+	}
+	assert.Equal(t, line, strings.ReplaceAll(folded, "\r\n ", ""))
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := NewWriter("xml", &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
@@ -0,0 +1,513 @@
+// Package importer turns workout history exported by other tracking apps
+// into store.Workout records.
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mhdph/go-start/internal/store"
+)
+
+const (
+	FormatStrong = "strong"
+	FormatHevy   = "hevy"
+)
+
+var ErrUnknownFormat = errors.New("unrecognised CSV format, expected a Strong or Hevy export")
+
+// RowError describes a CSV row that could not be imported. Row is the
+// 1-based line number, counting the header, and is left out for files
+// without rows.
+type RowError struct {
+	Row     int    `json:"row,omitempty"`
+	Message string `json:"message"`
+}
+
+// Result holds the workouts found in a file, in the order they appear.
+// Rows holds the line each CSV workout starts on.
+type Result struct {
+	Format   string
+	Workouts []*store.Workout
+	Rows     []int
+	Errors   []RowError
+}
+
+// DropInvalid moves the workouts that fail validation into Errors, against
+// the row they start on, so the rest of the file can still be imported.
+func (r *Result) DropInvalid() {
+	valid := r.Workouts[:0]
+	rows := []int{}
+	for i, workout := range r.Workouts {
+		row := 0
+		if i < len(r.Rows) {
+			row = r.Rows[i]
+		}
+		err := workout.Validate()
+		if err != nil {
+			r.Errors = append(r.Errors, RowError{Row: row, Message: err.Error()})
+			continue
+		}
+		valid = append(valid, workout)
+		rows = append(rows, row)
+	}
+	r.Workouts = valid
+	if len(r.Rows) > 0 {
+		r.Rows = rows
+	}
+	sort.SliceStable(r.Errors, func(i, j int) bool {
+		return r.Errors[i].Row < r.Errors[j].Row
+	})
+}
+
+type row struct {
+	start         time.Time
+	end           *time.Time
+	duration      int
+	title         string
+	workoutNotes  string
+	exercise      string
+	exerciseNotes string
+	set           store.WorkoutSet
+	seconds       *int
+	distance      *float64
+	superset      string
+}
+
+// entryType tells a lifted set apart from a run or a timed hold, which the
+// exports log as sets without reps.
+func (r row) entryType() string {
+	switch {
+	case r.distance != nil:
+		return store.EntryTypeCardio
+	case r.seconds != nil && (r.set.Reps == nil || *r.set.Reps == 0):
+		return store.EntryTypeTimed
+	default:
+		return store.EntryTypeStrength
+	}
+}
+
+type parser interface {
+	parse(record []string) (row, error)
+}
+
+// ParseCSV reads a Strong or Hevy CSV export. Weights are taken to be in
+// weightUnit unless the file says otherwise. Rows that cannot be parsed
+// are reported in Result.Errors and skipped; a file that cannot be read at
+// all returns an error.
+func ParseCSV(r io.Reader, weightUnit string) (*Result, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	result := &Result{Workouts: []*store.Workout{}, Rows: []int{}, Errors: []RowError{}}
+	var p parser
+	switch {
+	case hasColumns(columns, "date", "workout name", "exercise name"):
+		result.Format = FormatStrong
+		sp := strongParser{columns: columns, weightUnit: weightUnit}
+		if _, ok := columns["weight unit"]; ok {
+			// rows that name their unit are converted to kilograms
+			sp.toKilograms = true
+			weightUnit = store.UnitKilograms
+		}
+		p = sp
+	case hasColumns(columns, "title", "start_time", "exercise_title"):
+		// Hevy names the unit in the weight column, the parser converts
+		// it to kilograms
+		result.Format = FormatHevy
+		p = newHevyParser(columns)
+		weightUnit = store.UnitKilograms
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	byKey := map[string]*store.Workout{}
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				result.Errors = append(result.Errors, RowError{Row: line, Message: parseErr.Err.Error()})
+				continue
+			}
+			return nil, err
+		}
+
+		parsed, err := p.parse(record)
+		if err != nil {
+			result.Errors = append(result.Errors, RowError{Row: line, Message: err.Error()})
+			continue
+		}
+
+		key := parsed.title + "|" + parsed.start.Format(time.RFC3339)
+		workout, ok := byKey[key]
+		if !ok {
+			workout = &store.Workout{
+				Title:       parsed.title,
+				Description: parsed.workoutNotes,
+				Duration:    parsed.duration,
+				CreatedAt:   parsed.start,
+			}
+			byKey[key] = workout
+			result.Workouts = append(result.Workouts, workout)
+			result.Rows = append(result.Rows, line)
+		}
+		addSet(workout, parsed, weightUnit)
+	}
+
+	return result, nil
+}
+
+// addSet appends the row's set to the workout, starting a new entry
+// whenever the exercise or the kind of entry changes. Cardio rows add up
+// into one entry; timed holds count as sets of the longest hold.
+func addSet(workout *store.Workout, parsed row, weightUnit string) {
+	entryType := parsed.entryType()
+	n := len(workout.Entries)
+	if n == 0 || workout.Entries[n-1].ExerciesName != parsed.exercise || workout.Entries[n-1].Type != entryType {
+		workout.Entries = append(workout.Entries, store.WorkoutEntry{
+			ExerciesName: parsed.exercise,
+			Type:         entryType,
+			Notes:        parsed.exerciseNotes,
+			WeightUnit:   weightUnit,
+			OrderIndex:   n,
+		})
+		if parsed.superset != "" {
+			workout.Entries[n].Group = &store.EntryGroup{Label: parsed.superset, Type: store.GroupTypeSuperset, Rounds: 1}
+		}
+		n++
+	}
+
+	entry := &workout.Entries[n-1]
+	switch entryType {
+	case store.EntryTypeCardio:
+		entry.Sets = 1
+		distance := *parsed.distance
+		if entry.Distance != nil {
+			distance += *entry.Distance
+		}
+		entry.Distance = &distance
+		if parsed.seconds != nil {
+			total := *parsed.seconds
+			if entry.Duration != nil {
+				total += *entry.Duration
+			}
+			entry.Duration = &total
+		}
+
+	case store.EntryTypeTimed:
+		entry.Sets++
+		if entry.Duration == nil || *parsed.seconds > *entry.Duration {
+			entry.Duration = parsed.seconds
+		}
+		if parsed.set.Weight != nil && *parsed.set.Weight > 0 {
+			entry.Weight = parsed.set.Weight
+		}
+
+	default:
+		entry.SetLog = append(entry.SetLog, parsed.set)
+		if parsed.seconds != nil {
+			total := *parsed.seconds
+			if entry.Duration != nil {
+				total += *entry.Duration
+			}
+			entry.Duration = &total
+		}
+	}
+}
+
+type strongParser struct {
+	columns     map[string]int
+	weightUnit  string
+	toKilograms bool
+}
+
+var strongDurationPattern = regexp.MustCompile(`(\d+)\s*([hms])`)
+
+func (sp strongParser) parse(record []string) (row, error) {
+	get := func(name string) string { return column(record, sp.columns, name) }
+
+	var parsed row
+	var err error
+
+	parsed.start, err = time.Parse("2006-01-02 15:04:05", get("date"))
+	if err != nil {
+		return parsed, fmt.Errorf("invalid date %q", get("date"))
+	}
+	parsed.title = get("workout name")
+	parsed.exercise = get("exercise name")
+	if parsed.title == "" || parsed.exercise == "" {
+		return parsed, errors.New("workout name and exercise name are required")
+	}
+	parsed.workoutNotes = get("workout notes")
+	parsed.exerciseNotes = get("notes")
+
+	seconds := 0
+	for _, match := range strongDurationPattern.FindAllStringSubmatch(get("duration"), -1) {
+		n, _ := strconv.Atoi(match[1])
+		switch match[2] {
+		case "h":
+			seconds += n * 3600
+		case "m":
+			seconds += n * 60
+		case "s":
+			seconds += n
+		}
+	}
+	parsed.duration = (seconds + 30) / 60
+
+	switch order := strings.ToUpper(get("set order")); order {
+	case "W":
+		parsed.set.Type = store.SetTypeWarmup
+	case "D":
+		parsed.set.Type = store.SetTypeDrop
+	case "F":
+		parsed.set.Type = store.SetTypeFailure
+	default:
+		parsed.set.Type = store.SetTypeWorking
+	}
+
+	parsed.set.Weight, err = parseFloat(get("weight"))
+	if err != nil {
+		return parsed, fmt.Errorf("invalid weight %q", get("weight"))
+	}
+	if sp.toKilograms && parsed.set.Weight != nil {
+		unit, err := parseWeightUnit(get("weight unit"), sp.weightUnit)
+		if err != nil {
+			return parsed, err
+		}
+		kilograms := store.ToKilograms(*parsed.set.Weight, unit)
+		parsed.set.Weight = &kilograms
+	}
+	parsed.set.Reps, err = parseInt(get("reps"))
+	if err != nil {
+		return parsed, fmt.Errorf("invalid reps %q", get("reps"))
+	}
+	parsed.set.RPE, err = parseFloat(get("rpe"))
+	if err != nil {
+		return parsed, fmt.Errorf("invalid rpe %q", get("rpe"))
+	}
+	parsed.seconds, err = parseInt(get("seconds"))
+	if err != nil {
+		return parsed, fmt.Errorf("invalid seconds %q", get("seconds"))
+	}
+	if parsed.seconds != nil && *parsed.seconds == 0 {
+		parsed.seconds = nil
+	}
+	metersPerUnit, err := metersPer(get("distance unit"))
+	if err != nil {
+		return parsed, err
+	}
+	parsed.distance, err = parseDistance(get("distance"), metersPerUnit)
+	if err != nil {
+		return parsed, fmt.Errorf("invalid distance %q", get("distance"))
+	}
+
+	return parsed, nil
+}
+
+type hevyParser struct {
+	columns     map[string]int
+	weightIndex string
+	toKilograms bool
+}
+
+func newHevyParser(columns map[string]int) hevyParser {
+	hp := hevyParser{columns: columns, weightIndex: "weight_kg"}
+	if _, ok := columns["weight_lbs"]; ok {
+		hp.weightIndex = "weight_lbs"
+		hp.toKilograms = true
+	}
+	return hp
+}
+
+var hevyTimeLayouts = []string{"2 Jan 2006, 15:04", "2006-01-02 15:04:05", time.RFC3339}
+
+func (hp hevyParser) parse(record []string) (row, error) {
+	get := func(name string) string { return column(record, hp.columns, name) }
+
+	var parsed row
+	var err error
+
+	parsed.start, err = parseHevyTime(get("start_time"))
+	if err != nil {
+		return parsed, fmt.Errorf("invalid start_time %q", get("start_time"))
+	}
+	if value := get("end_time"); value != "" {
+		end, err := parseHevyTime(value)
+		if err != nil {
+			return parsed, fmt.Errorf("invalid end_time %q", value)
+		}
+		parsed.end = &end
+		parsed.duration = int(end.Sub(parsed.start).Minutes())
+	}
+	parsed.title = get("title")
+	parsed.exercise = get("exercise_title")
+	if parsed.title == "" || parsed.exercise == "" {
+		return parsed, errors.New("title and exercise_title are required")
+	}
+	parsed.workoutNotes = get("description")
+	parsed.exerciseNotes = get("exercise_notes")
+	parsed.superset = get("superset_id")
+
+	switch get("set_type") {
+	case "warmup":
+		parsed.set.Type = store.SetTypeWarmup
+	case "dropset":
+		parsed.set.Type = store.SetTypeDrop
+	case "failure":
+		parsed.set.Type = store.SetTypeFailure
+	default:
+		parsed.set.Type = store.SetTypeWorking
+	}
+
+	parsed.set.Weight, err = parseFloat(get(hp.weightIndex))
+	if err != nil {
+		return parsed, fmt.Errorf("invalid %s %q", hp.weightIndex, get(hp.weightIndex))
+	}
+	if hp.toKilograms && parsed.set.Weight != nil {
+		kilograms := store.ToKilograms(*parsed.set.Weight, store.UnitPounds)
+		parsed.set.Weight = &kilograms
+	}
+	parsed.set.Reps, err = parseInt(get("reps"))
+	if err != nil {
+		return parsed, fmt.Errorf("invalid reps %q", get("reps"))
+	}
+	parsed.set.RPE, err = parseFloat(get("rpe"))
+	if err != nil {
+		return parsed, fmt.Errorf("invalid rpe %q", get("rpe"))
+	}
+	parsed.seconds, err = parseInt(get("duration_seconds"))
+	if err != nil {
+		return parsed, fmt.Errorf("invalid duration_seconds %q", get("duration_seconds"))
+	}
+	if parsed.seconds != nil && *parsed.seconds == 0 {
+		parsed.seconds = nil
+	}
+	if value := get("distance_km"); value != "" {
+		parsed.distance, err = parseDistance(value, 1000)
+	} else {
+		parsed.distance, err = parseDistance(get("distance_miles"), 1609.344)
+	}
+	if err != nil {
+		return parsed, errors.New("invalid distance")
+	}
+
+	return parsed, nil
+}
+
+func parseHevyTime(value string) (time.Time, error) {
+	var err error
+	for _, layout := range hevyTimeLayouts {
+		var t time.Time
+		t, err = time.Parse(layout, value)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// parseWeightUnit reads a Strong weight unit, falling back to the unit
+// the user picked for rows that leave it blank.
+func parseWeightUnit(value, fallback string) (string, error) {
+	switch strings.ToLower(value) {
+	case "":
+		return fallback, nil
+	case "kg", "kgs":
+		return store.UnitKilograms, nil
+	case "lb", "lbs":
+		return store.UnitPounds, nil
+	default:
+		return "", fmt.Errorf("invalid weight unit %q", value)
+	}
+}
+
+// metersPer returns the length of a Strong distance unit in meters.
+// Distances without a unit are in kilometers.
+func metersPer(unit string) (float64, error) {
+	switch strings.ToLower(unit) {
+	case "", "km":
+		return 1000, nil
+	case "mi", "miles":
+		return 1609.344, nil
+	case "m":
+		return 1, nil
+	default:
+		return 0, fmt.Errorf("invalid distance unit %q", unit)
+	}
+}
+
+func hasColumns(columns map[string]int, names ...string) bool {
+	for _, name := range names {
+		if _, ok := columns[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func column(record []string, columns map[string]int, name string) string {
+	i, ok := columns[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func parseFloat(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// parseDistance reads a distance in the export's unit and returns it in
+// meters. Zero means no distance was logged.
+func parseDistance(value string, metersPerUnit float64) (*float64, error) {
+	distance, err := parseFloat(value)
+	if err != nil || distance == nil || *distance == 0 {
+		return nil, err
+	}
+	meters := *distance * metersPerUnit
+	return &meters, nil
+}
+
+func parseInt(value string) (*int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	i := int(f)
+	return &i, nil
+}
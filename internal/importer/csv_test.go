@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStrongCSV(t *testing.T) {
+	input := `Date,Workout Name,Duration,Exercise Name,Set Order,Weight,Reps,Distance,Seconds,Notes,Workout Notes,RPE
+2024-05-01 18:00:00,Push day,1h 5m,Bench Press (Barbell),W,60,10,0,0,,Felt good,
+2024-05-01 18:00:00,Push day,1h 5m,Bench Press (Barbell),1,100,5,0,0,,Felt good,8
+2024-05-01 18:00:00,Push day,1h 5m,Plank,1,0,0,0,60,,Felt good,
+2024-05-01 18:00:00,Push day,1h 5m,Bench Press (Barbell),2,heavy,5,0,0,,Felt good,
+2024-05-03 07:30:00,Run,30m,Running,1,,,5,1800,,,
+`
+	result, err := ParseCSV(strings.NewReader(input), store.UnitPounds)
+	require.NoError(t, err)
+
+	assert.Equal(t, FormatStrong, result.Format)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 5, result.Errors[0].Row)
+
+	require.Len(t, result.Workouts, 2)
+	push := result.Workouts[0]
+	assert.Equal(t, "Push day", push.Title)
+	assert.Equal(t, "Felt good", push.Description)
+	assert.Equal(t, 65, push.Duration)
+	assert.Equal(t, time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC), push.CreatedAt)
+
+	require.Len(t, push.Entries, 2)
+	bench := push.Entries[0]
+	assert.Equal(t, "Bench Press (Barbell)", bench.ExerciesName)
+	assert.Equal(t, store.UnitPounds, bench.WeightUnit)
+	require.Len(t, bench.SetLog, 2)
+	assert.Equal(t, store.SetTypeWarmup, bench.SetLog[0].Type)
+	assert.Equal(t, 100.0, *bench.SetLog[1].Weight)
+	assert.Equal(t, 8.0, *bench.SetLog[1].RPE)
+
+	plank := push.Entries[1]
+	assert.Equal(t, store.EntryTypeTimed, plank.Type)
+	assert.Empty(t, plank.SetLog)
+	require.NotNil(t, plank.Duration)
+	assert.Equal(t, 60, *plank.Duration)
+	assert.Equal(t, 1, plank.OrderIndex)
+
+	run := result.Workouts[1].Entries[0]
+	assert.Equal(t, store.EntryTypeCardio, run.Type)
+	require.NotNil(t, run.Distance)
+	assert.Equal(t, 5000.0, *run.Distance)
+	assert.Equal(t, 1800, *run.Duration)
+}
+
+func TestParseHevyCSV(t *testing.T) {
+	input := `"title","start_time","end_time","description","exercise_title","superset_id","exercise_notes","set_index","set_type","weight_lbs","reps","distance_miles","duration_seconds","rpe"
+"Legs","12 Mar 2024, 09:00","12 Mar 2024, 10:15","","Squat (Barbell)",,"","0","warmup","135","5",,,
+"Legs","12 Mar 2024, 09:00","12 Mar 2024, 10:15","","Squat (Barbell)",,"","1","normal","225","5",,,
+"Legs","12 Mar 2024, 09:00","12 Mar 2024, 10:15","","Squat (Barbell)",,"","2","dropset","185","8",,,
+"Legs","12 Mar 2024, 09:00","12 Mar 2024, 10:15","","Leg Extension",0,"","0","normal","100","12",,,
+"Legs","12 Mar 2024, 09:00","12 Mar 2024, 10:15","","Leg Curl",0,"","0","normal","80","12",,,
+`
+	result, err := ParseCSV(strings.NewReader(input), store.UnitKilograms)
+	require.NoError(t, err)
+
+	assert.Equal(t, FormatHevy, result.Format)
+	assert.Empty(t, result.Errors)
+	require.Len(t, result.Workouts, 1)
+
+	legs := result.Workouts[0]
+	assert.Equal(t, 75, legs.Duration)
+	require.Len(t, legs.Entries, 3)
+	assert.Nil(t, legs.Entries[0].Group)
+	require.NotNil(t, legs.Entries[1].Group)
+	assert.Equal(t, legs.Entries[1].Group, legs.Entries[2].Group)
+	assert.Equal(t, store.GroupTypeSuperset, legs.Entries[2].Group.Type)
+	squat := legs.Entries[0]
+	assert.Equal(t, store.UnitKilograms, squat.WeightUnit)
+	require.Len(t, squat.SetLog, 3)
+	assert.Equal(t, store.SetTypeDrop, squat.SetLog[2].Type)
+	assert.InDelta(t, 102.058, *squat.SetLog[1].Weight, 0.001)
+}
+
+func TestParseUnknownCSV(t *testing.T) {
+	_, err := ParseCSV(strings.NewReader("a,b,c\n1,2,3\n"), store.UnitKilograms)
+	assert.ErrorIs(t, err, ErrUnknownFormat)
+}
+
+func TestParseStrongCSVUnits(t *testing.T) {
+	input := `Date,Workout Name,Duration,Exercise Name,Set Order,Weight,Weight Unit,Reps,Distance,Distance Unit,Seconds,Notes,Workout Notes,RPE
+2024-05-01 18:00:00,Push day,1h,Bench Press (Barbell),1,225,lbs,5,0,,0,,,
+2024-05-01 18:00:00,Push day,1h,Bench Press (Barbell),2,100,kg,5,0,,0,,,
+2024-05-01 18:00:00,Push day,1h,Bench Press (Barbell),3,50,,5,0,,0,,,
+2024-05-03 07:30:00,Run,30m,Running,1,,,,3.1,mi,1800,,,
+2024-05-04 07:30:00,Run,30m,Running,1,,,,5,furlongs,1800,,,
+`
+	result, err := ParseCSV(strings.NewReader(input), store.UnitPounds)
+	require.NoError(t, err)
+
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, 6, result.Errors[0].Row)
+
+	require.Len(t, result.Workouts, 2)
+	bench := result.Workouts[0].Entries[0]
+	assert.Equal(t, store.UnitKilograms, bench.WeightUnit)
+	require.Len(t, bench.SetLog, 3)
+	assert.InDelta(t, 102.058, *bench.SetLog[0].Weight, 0.001)
+	assert.Equal(t, 100.0, *bench.SetLog[1].Weight)
+	// a blank unit falls back to the one picked for the import
+	assert.InDelta(t, 22.680, *bench.SetLog[2].Weight, 0.001)
+
+	run := result.Workouts[1].Entries[0]
+	require.NotNil(t, run.Distance)
+	assert.InDelta(t, 4988.966, *run.Distance, 0.001)
+}
+
+func TestDropInvalid(t *testing.T) {
+	input := `Date,Workout Name,Duration,Exercise Name,Set Order,Weight,Reps,Distance,Seconds,Notes,Workout Notes,RPE
+2024-05-01 18:00:00,Push day,1h,Bench Press (Barbell),1,100,5,0,0,,,
+2024-05-02 18:00:00,Pull day,1h,Deadlift (Barbell),1,-100,5,0,0,,,
+2024-05-02 18:00:00,Pull day,1h,Deadlift (Barbell),2,140,5,0,0,,,
+2024-05-03 18:00:00,Leg day,1h,Squat (Barbell),1,120,5,0,0,,,
+2024-05-03 18:00:00,Leg day,1h,Squat (Barbell),2,bad,5,0,0,,,
+`
+	result, err := ParseCSV(strings.NewReader(input), store.UnitKilograms)
+	require.NoError(t, err)
+	require.Len(t, result.Workouts, 3)
+	assert.Equal(t, []int{2, 3, 5}, result.Rows)
+
+	result.DropInvalid()
+
+	require.Len(t, result.Workouts, 2)
+	assert.Equal(t, "Push day", result.Workouts[0].Title)
+	assert.Equal(t, "Leg day", result.Workouts[1].Title)
+	assert.Equal(t, []int{2, 5}, result.Rows)
+
+	require.Len(t, result.Errors, 2)
+	assert.Equal(t, 3, result.Errors[0].Row)
+	assert.Contains(t, result.Errors[0].Message, "weight")
+	assert.Equal(t, 6, result.Errors[1].Row)
+}
@@ -0,0 +1,299 @@
+package importer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mhdph/go-start/internal/store"
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/geo"
+)
+
+const (
+	FormatGPX = "gpx"
+	FormatTCX = "tcx"
+)
+
+var ErrEmptyTrack = errors.New("the file has no timed track points")
+
+// TrackPoint is one recorded position. Distance is only set by devices
+// that measure it themselves (TCX).
+type TrackPoint struct {
+	Time      time.Time
+	Point     orb.Point
+	HasPoint  bool
+	Elevation *float64
+	Distance  *float64
+	HeartRate *int
+}
+
+// Activity is a GPS or sensor recording of a single cardio session.
+type Activity struct {
+	Name   string
+	Sport  string
+	Points []TrackPoint
+}
+
+type gpxFile struct {
+	Metadata struct {
+		Name string `xml:"name"`
+	} `xml:"metadata"`
+	Tracks []struct {
+		Name     string `xml:"name"`
+		Type     string `xml:"type"`
+		Segments []struct {
+			Points []struct {
+				Lat       float64    `xml:"lat,attr"`
+				Lon       float64    `xml:"lon,attr"`
+				Elevation *float64   `xml:"ele"`
+				Time      *time.Time `xml:"time"`
+				HeartRate *int       `xml:"extensions>TrackPointExtension>hr"`
+			} `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+// ParseGPX reads every track of a GPX 1.1 file into one activity.
+func ParseGPX(r io.Reader) (*Activity, error) {
+	var file gpxFile
+	err := xml.NewDecoder(r).Decode(&file)
+	if err != nil {
+		return nil, fmt.Errorf("parse gpx: %w", err)
+	}
+
+	activity := &Activity{Name: file.Metadata.Name}
+	for _, track := range file.Tracks {
+		if activity.Name == "" {
+			activity.Name = track.Name
+		}
+		if activity.Sport == "" {
+			activity.Sport = track.Type
+		}
+		for _, segment := range track.Segments {
+			for _, p := range segment.Points {
+				if p.Time == nil {
+					continue
+				}
+				activity.Points = append(activity.Points, TrackPoint{
+					Time:      *p.Time,
+					Point:     orb.Point{p.Lon, p.Lat},
+					HasPoint:  true,
+					Elevation: p.Elevation,
+					HeartRate: p.HeartRate,
+				})
+			}
+		}
+	}
+
+	if len(activity.Points) == 0 {
+		return nil, ErrEmptyTrack
+	}
+	return activity, nil
+}
+
+type tcxFile struct {
+	Activities []struct {
+		Sport string `xml:"Sport,attr"`
+		Notes string `xml:"Notes"`
+		Laps  []struct {
+			Points []struct {
+				Time     time.Time `xml:"Time"`
+				Position *struct {
+					Lat float64 `xml:"LatitudeDegrees"`
+					Lon float64 `xml:"LongitudeDegrees"`
+				} `xml:"Position"`
+				Altitude  *float64 `xml:"AltitudeMeters"`
+				Distance  *float64 `xml:"DistanceMeters"`
+				HeartRate *int     `xml:"HeartRateBpm>Value"`
+			} `xml:"Track>Trackpoint"`
+		} `xml:"Lap"`
+	} `xml:"Activities>Activity"`
+}
+
+// ParseTCX reads the first activity of a Garmin Training Center file.
+func ParseTCX(r io.Reader) (*Activity, error) {
+	var file tcxFile
+	err := xml.NewDecoder(r).Decode(&file)
+	if err != nil {
+		return nil, fmt.Errorf("parse tcx: %w", err)
+	}
+	if len(file.Activities) == 0 {
+		return nil, ErrEmptyTrack
+	}
+
+	source := file.Activities[0]
+	activity := &Activity{Name: source.Notes, Sport: source.Sport}
+	for _, lap := range source.Laps {
+		for _, p := range lap.Points {
+			if p.Time.IsZero() {
+				continue
+			}
+			point := TrackPoint{
+				Time:      p.Time,
+				Elevation: p.Altitude,
+				Distance:  p.Distance,
+				HeartRate: p.HeartRate,
+			}
+			if p.Position != nil {
+				point.Point = orb.Point{p.Position.Lon, p.Position.Lat}
+				point.HasPoint = true
+			}
+			activity.Points = append(activity.Points, point)
+		}
+	}
+
+	if len(activity.Points) == 0 {
+		return nil, ErrEmptyTrack
+	}
+	return activity, nil
+}
+
+// Summarize derives distance, pace, elevation gain, per-kilometre splits
+// and the route from the recorded points.
+func (a *Activity) Summarize() *store.CardioSummary {
+	points := a.Points
+	first, last := points[0], points[len(points)-1]
+
+	summary := &store.CardioSummary{
+		Splits: []store.Split{},
+		Route:  store.NewRoute(orb.LineString{}),
+	}
+	summary.DurationSeconds = int(last.Time.Sub(first.Time).Seconds())
+
+	heartRateTotal, heartRateCount := 0, 0
+	distance := 0.0
+	splitStart := first.Time
+	nextSplit := 1000.0
+	for i, point := range points {
+		if point.HasPoint {
+			summary.Route.Coordinates = append(summary.Route.Coordinates, point.Point)
+		}
+		if point.HeartRate != nil {
+			heartRateTotal += *point.HeartRate
+			heartRateCount++
+		}
+		if i == 0 {
+			continue
+		}
+
+		previous := points[i-1]
+		previousDistance := distance
+		switch {
+		case point.Distance != nil && previous.Distance != nil:
+			distance = *point.Distance
+		case point.HasPoint && previous.HasPoint:
+			distance += geo.Distance(previous.Point, point.Point)
+		}
+
+		if point.Elevation != nil && previous.Elevation != nil && *point.Elevation > *previous.Elevation {
+			summary.ElevationGain += *point.Elevation - *previous.Elevation
+		}
+
+		// A segment can cross one or more kilometre marks; the time at each
+		// mark is interpolated between the two points.
+		for distance >= nextSplit {
+			fraction := (nextSplit - previousDistance) / (distance - previousDistance)
+			crossed := previous.Time.Add(time.Duration(fraction * float64(point.Time.Sub(previous.Time))))
+			summary.Splits = append(summary.Splits, store.Split{
+				Kilometer:       len(summary.Splits) + 1,
+				DistanceMeters:  1000,
+				DurationSeconds: int(math.Round(crossed.Sub(splitStart).Seconds())),
+			})
+			splitStart = crossed
+			nextSplit += 1000
+		}
+	}
+
+	if remainder := distance - (nextSplit - 1000); remainder >= 1 {
+		summary.Splits = append(summary.Splits, store.Split{
+			Kilometer:       len(summary.Splits) + 1,
+			DistanceMeters:  math.Round(remainder),
+			DurationSeconds: int(math.Round(last.Time.Sub(splitStart).Seconds())),
+		})
+	}
+
+	summary.DistanceMeters = math.Round(distance)
+	summary.ElevationGain = math.Round(summary.ElevationGain)
+	if distance > 0 && summary.DurationSeconds > 0 {
+		summary.AvgPaceSecondsPerKm = math.Round(float64(summary.DurationSeconds) / (distance / 1000))
+		summary.AvgSpeedKmh = math.Round(distance/1000/(float64(summary.DurationSeconds)/3600)*100) / 100
+	}
+	if heartRateCount > 0 {
+		avg := heartRateTotal / heartRateCount
+		summary.AvgHeartRate = &avg
+	}
+	return summary
+}
+
+// Workout turns the activity into a workout with a single cardio entry
+// named after the sport, so it maps onto the exercise catalog.
+func (a *Activity) Workout() *store.Workout {
+	summary := a.Summarize()
+	sport := sportName(a.Sport)
+
+	title := strings.TrimSpace(a.Name)
+	if title == "" {
+		title = sport
+	}
+
+	duration := summary.DurationSeconds
+	return &store.Workout{
+		Title:     title,
+		Duration:  (duration + 30) / 60,
+		CreatedAt: a.Points[0].Time,
+		Cardio:    summary,
+		Entries: []store.WorkoutEntry{
+			{ExerciesName: sport, Type: store.EntryTypeCardio, Sets: 1, Duration: &duration, Distance: &summary.DistanceMeters, AvgHeartRate: summary.AvgHeartRate},
+		},
+	}
+}
+
+func sportName(sport string) string {
+	switch s := strings.ToLower(sport); {
+	case strings.Contains(s, "run"):
+		return "Running"
+	case strings.Contains(s, "bik"), strings.Contains(s, "cycl"), strings.Contains(s, "ride"):
+		return "Cycling"
+	case strings.Contains(s, "walk"):
+		return "Walking"
+	case strings.Contains(s, "hik"):
+		return "Hiking"
+	case strings.Contains(s, "swim"):
+		return "Swimming"
+	case strings.Contains(s, "row"):
+		return "Rowing"
+	default:
+		return "Cardio"
+	}
+}
+
+// ParseFile picks a parser from the file name: GPX and TCX recordings
+// become a single cardio workout, anything else is read as a CSV export.
+func ParseFile(r io.Reader, filename, weightUnit string) (*Result, error) {
+	var parse func(io.Reader) (*Activity, error)
+	format := ""
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".gpx":
+		parse, format = ParseGPX, FormatGPX
+	case ".tcx":
+		parse, format = ParseTCX, FormatTCX
+	default:
+		return ParseCSV(r, weightUnit)
+	}
+
+	activity, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Format:   format,
+		Workouts: []*store.Workout{activity.Workout()},
+		Errors:   []RowError{},
+	}, nil
+}
@@ -0,0 +1,86 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGPX(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" xmlns="http://www.topografix.com/GPX/1/1" xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v1">
+  <trk>
+    <name>Morning Run</name>
+    <type>running</type>
+    <trkseg>
+      <trkpt lat="0" lon="0"><ele>10</ele><time>2024-06-01T07:00:00Z</time><extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>140</gpxtpx:hr></gpxtpx:TrackPointExtension></extensions></trkpt>
+      <trkpt lat="0" lon="0.005"><ele>15</ele><time>2024-06-01T07:03:00Z</time><extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>150</gpxtpx:hr></gpxtpx:TrackPointExtension></extensions></trkpt>
+      <trkpt lat="0" lon="0.01"><ele>12</ele><time>2024-06-01T07:06:00Z</time></trkpt>
+      <trkpt lat="0" lon="0.015"><ele>20</ele><time>2024-06-01T07:09:00Z</time></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+	result, err := ParseFile(strings.NewReader(input), "run.GPX", "")
+	require.NoError(t, err)
+	assert.Equal(t, FormatGPX, result.Format)
+	require.Len(t, result.Workouts, 1)
+
+	workout := result.Workouts[0]
+	assert.Equal(t, "Morning Run", workout.Title)
+	assert.Equal(t, 9, workout.Duration)
+	assert.Equal(t, time.Date(2024, 6, 1, 7, 0, 0, 0, time.UTC), workout.CreatedAt)
+	require.Len(t, workout.Entries, 1)
+	assert.Equal(t, "Running", workout.Entries[0].ExerciesName)
+
+	cardio := workout.Cardio
+	require.NotNil(t, cardio)
+	assert.Equal(t, 540, cardio.DurationSeconds)
+	assert.InDelta(t, 1668, cardio.DistanceMeters, 5)
+	assert.Equal(t, 13.0, cardio.ElevationGain)
+	assert.InDelta(t, 324, cardio.AvgPaceSecondsPerKm, 2)
+	require.NotNil(t, cardio.AvgHeartRate)
+	assert.Equal(t, 145, *cardio.AvgHeartRate)
+	require.Len(t, cardio.Splits, 2)
+	assert.Equal(t, 1000.0, cardio.Splits[0].DistanceMeters)
+	assert.InDelta(t, 324, cardio.Splits[0].DurationSeconds, 2)
+	assert.Equal(t, "LineString", cardio.Route.Type)
+	assert.Len(t, cardio.Route.Coordinates, 4)
+}
+
+func TestParseTCXPrefersDeviceDistance(t *testing.T) {
+	input := `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2">
+  <Activities>
+    <Activity Sport="Biking">
+      <Lap>
+        <Track>
+          <Trackpoint><Time>2024-06-02T17:00:00Z</Time><DistanceMeters>0</DistanceMeters></Trackpoint>
+          <Trackpoint><Time>2024-06-02T17:30:00Z</Time><DistanceMeters>15000</DistanceMeters><HeartRateBpm><Value>130</Value></HeartRateBpm></Trackpoint>
+        </Track>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`
+
+	result, err := ParseFile(strings.NewReader(input), "ride.tcx", "")
+	require.NoError(t, err)
+	assert.Equal(t, FormatTCX, result.Format)
+
+	workout := result.Workouts[0]
+	assert.Equal(t, "Cycling", workout.Title)
+	assert.Equal(t, 30, workout.Duration)
+	assert.Equal(t, 15000.0, workout.Cardio.DistanceMeters)
+	assert.Equal(t, 30.0, workout.Cardio.AvgSpeedKmh)
+	assert.Empty(t, workout.Cardio.Route.Coordinates)
+	require.Len(t, workout.Cardio.Splits, 15)
+	assert.Equal(t, 120, workout.Cardio.Splits[14].DurationSeconds)
+}
+
+func TestParseGPXWithoutPoints(t *testing.T) {
+	_, err := ParseGPX(strings.NewReader(`<gpx><trk><trkseg></trkseg></trk></gpx>`))
+	assert.ErrorIs(t, err, ErrEmptyTrack)
+}
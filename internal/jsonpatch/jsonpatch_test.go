@@ -0,0 +1,110 @@
+package jsonpatch
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePatch(t *testing.T) {
+	doc := `{"title":"Legs","description":"heavy","tags":["a","b"],"cardio":{"distance":5,"pace":300}}`
+	patch := `{"title":"Leg day","description":null,"tags":["c"],"cardio":{"pace":null,"splits":[]}}`
+
+	out, err := MergePatch([]byte(doc), []byte(patch))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"title":"Leg day","tags":["c"],"cardio":{"distance":5,"splits":[]}}`, string(out))
+
+	_, err = MergePatch([]byte(doc), []byte(`{`))
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+}
+
+func TestApply(t *testing.T) {
+	doc := `{"title":"Push","entries":[{"exercise_name":"Bench","reps":5},{"exercise_name":"Dips","reps":10}],"a/b":1}`
+
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+		err   error
+	}{
+		{
+			name:  "replace an entry field by index",
+			patch: `[{"op":"test","path":"/entries/0/reps","value":5.0},{"op":"replace","path":"/entries/0/reps","value":8}]`,
+			want:  `{"title":"Push","entries":[{"exercise_name":"Bench","reps":8},{"exercise_name":"Dips","reps":10}],"a/b":1}`,
+		},
+		{
+			name:  "insert, append and remove entries",
+			patch: `[{"op":"add","path":"/entries/0","value":{"exercise_name":"Press"}},{"op":"add","path":"/entries/-","value":{"exercise_name":"Fly"}},{"op":"remove","path":"/entries/2"}]`,
+			want:  `{"title":"Push","entries":[{"exercise_name":"Press"},{"exercise_name":"Bench","reps":5},{"exercise_name":"Fly"}],"a/b":1}`,
+		},
+		{
+			name:  "move and copy",
+			patch: `[{"op":"move","from":"/entries/1","path":"/entries/0"},{"op":"copy","from":"/title","path":"/description"}]`,
+			want:  `{"title":"Push","description":"Push","entries":[{"exercise_name":"Dips","reps":10},{"exercise_name":"Bench","reps":5}],"a/b":1}`,
+		},
+		{
+			name:  "escaped pointer",
+			patch: `[{"op":"remove","path":"/a~1b"}]`,
+			want:  `{"title":"Push","entries":[{"exercise_name":"Bench","reps":5},{"exercise_name":"Dips","reps":10}]}`,
+		},
+		{
+			name:  "failed test",
+			patch: `[{"op":"replace","path":"/title","value":"Pull"},{"op":"test","path":"/title","value":"Push"}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "index out of range",
+			patch: `[{"op":"replace","path":"/entries/2/reps","value":1}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "leading zero index",
+			patch: `[{"op":"remove","path":"/entries/01"}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "replace missing key",
+			patch: `[{"op":"replace","path":"/notes","value":"x"}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "missing value",
+			patch: `[{"op":"add","path":"/notes"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "unknown op",
+			patch: `[{"op":"merge","path":"/title"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "move into own child",
+			patch: `[{"op":"move","from":"/entries","path":"/entries/0"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "not an array",
+			patch: `{"op":"remove","path":"/title"}`,
+			err:   ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Apply([]byte(doc), []byte(tt.patch))
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(out))
+		})
+	}
+}
+
+func TestApplyNullValue(t *testing.T) {
+	out, err := Apply([]byte(`{"notes":"x"}`), []byte(`[{"op":"replace","path":"/notes","value":null}]`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"notes":null}`, string(out))
+}
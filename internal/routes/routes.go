@@ -10,13 +10,71 @@ func SetupRoutes(app *app.Application) *chi.Mux {
 
 	r.Group(func(r chi.Router) {
 		r.Use(app.Middleware.Autheniticate)
+		r.Get("/workouts", app.Middleware.RequireUser(app.WorkoutHandler.HandleListWorkouts))
+		r.Get("/workouts/export", app.Middleware.RequireUser(app.WorkoutHandler.HandleExportWorkouts))
+		r.Get("/workouts/trash", app.Middleware.RequireUser(app.WorkoutHandler.HandleListTrash))
 		r.Get("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleGetWorkoutByID))
-		r.Post("/workouts", app.WorkoutHandler.HandleCreateWorkout)
-		r.Put("/workouts/{id}", app.WorkoutHandler.HandleUpdateWorkoutById)
-		r.Delete("/workouts/{id}", app.WorkoutHandler.HandleDeleteWorkoutById)
+		r.Post("/workouts", app.Middleware.RequireUser(app.WorkoutHandler.HandleCreateWorkout))
+		r.Post("/workouts:batch", app.Middleware.RequireUser(app.WorkoutHandler.HandleBatchWorkouts))
+		r.Post("/workouts/import", app.Middleware.RequireUser(app.WorkoutHandler.HandleImportWorkouts))
+		r.Put("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleReplaceWorkoutById))
+		r.Patch("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleUpdateWorkoutById))
+		r.Delete("/workouts/{id}", app.Middleware.RequireUser(app.WorkoutHandler.HandleDeleteWorkoutById))
+		r.Post("/workouts/{id}/entries", app.Middleware.RequireUser(app.EntryHandler.HandleCreateEntry))
+		r.Post("/workouts/{id}/entries/reorder", app.Middleware.RequireUser(app.EntryHandler.HandleReorderEntries))
+		r.Put("/workouts/{id}/entries/{entryID}", app.Middleware.RequireUser(app.EntryHandler.HandleUpdateEntry))
+		r.Delete("/workouts/{id}/entries/{entryID}", app.Middleware.RequireUser(app.EntryHandler.HandleDeleteEntry))
+		r.Post("/workouts/{id}/restore", app.Middleware.RequireUser(app.WorkoutHandler.HandleRestoreWorkout))
+		r.Get("/workouts/{id}/revisions", app.Middleware.RequireUser(app.RevisionHandler.HandleListRevisions))
+		r.Get("/workouts/{id}/revisions/diff", app.Middleware.RequireUser(app.RevisionHandler.HandleDiffRevisions))
+		r.Get("/workouts/{id}/revisions/{revision}", app.Middleware.RequireUser(app.RevisionHandler.HandleGetRevision))
+		r.Post("/workouts/{id}/revisions/{revision}/revert", app.Middleware.RequireUser(app.RevisionHandler.HandleRevertRevision))
+		r.Get("/workouts/{id}/share", app.Middleware.RequireUser(app.ShareHandler.HandleGetShare))
+		r.Post("/workouts/{id}/share", app.Middleware.RequireUser(app.ShareHandler.HandleCreateShare))
+		r.Delete("/workouts/{id}/share", app.Middleware.RequireUser(app.ShareHandler.HandleRevokeShare))
+		r.Get("/workouts/{id}/comments", app.Middleware.RequireUser(app.CommentHandler.HandleGetComments))
+		r.Post("/workouts/{id}/comments", app.Middleware.RequireUser(app.CommentHandler.HandleCreateComment))
+		r.Delete("/workouts/{id}/comments/{commentID}", app.Middleware.RequireUser(app.CommentHandler.HandleDeleteComment))
+		r.Get("/workouts/{id}/reactions", app.Middleware.RequireUser(app.CommentHandler.HandleGetReactions))
+		r.Post("/workouts/{id}/reactions", app.Middleware.RequireUser(app.CommentHandler.HandleAddReaction))
+		r.Delete("/workouts/{id}/reactions", app.Middleware.RequireUser(app.CommentHandler.HandleRemoveReaction))
+
+		r.Get("/templates", app.Middleware.RequireUser(app.TemplateHandler.HandleListTemplates))
+		r.Post("/templates", app.Middleware.RequireUser(app.TemplateHandler.HandleCreateTemplate))
+		r.Get("/templates/{id}", app.Middleware.RequireUser(app.TemplateHandler.HandleGetTemplateByID))
+		r.Delete("/templates/{id}", app.Middleware.RequireUser(app.TemplateHandler.HandleDeleteTemplateByID))
+		r.Post("/templates/{id}/start", app.Middleware.RequireUser(app.TemplateHandler.HandleStartTemplate))
+
+		r.Get("/programs", app.Middleware.RequireUser(app.ProgramHandler.HandleListPrograms))
+		r.Post("/programs", app.Middleware.RequireUser(app.ProgramHandler.HandleCreateProgram))
+		r.Get("/programs/{id}", app.Middleware.RequireUser(app.ProgramHandler.HandleGetProgramByID))
+		r.Delete("/programs/{id}", app.Middleware.RequireUser(app.ProgramHandler.HandleDeleteProgramByID))
+		r.Get("/calendar", app.Middleware.RequireUser(app.ProgramHandler.HandleGetCalendar))
+
+		r.Put("/users/me/preferences", app.Middleware.RequireUser(app.UserHandler.HandleUpdatePreferences))
+		r.Get("/users/me/records", app.Middleware.RequireUser(app.RecordHandler.HandleGetMyRecords))
+		r.Get("/users/me/analytics", app.Middleware.RequireUser(app.AnalyticsHandler.HandleGetMyAnalytics))
+		r.Get("/users/me/goals", app.Middleware.RequireUser(app.GoalHandler.HandleGetMyGoals))
+		r.Post("/users/me/goals", app.Middleware.RequireUser(app.GoalHandler.HandleCreateGoal))
+		r.Delete("/users/me/goals/{id}", app.Middleware.RequireUser(app.GoalHandler.HandleDeleteGoal))
+
+		r.Post("/users/{id}/follow", app.Middleware.RequireUser(app.SocialHandler.HandleFollowUser))
+		r.Delete("/users/{id}/follow", app.Middleware.RequireUser(app.SocialHandler.HandleUnfollowUser))
+		r.Get("/users/{id}/followers", app.Middleware.RequireUser(app.SocialHandler.HandleGetFollowers))
+		r.Get("/users/{id}/following", app.Middleware.RequireUser(app.SocialHandler.HandleGetFollowing))
+		r.Get("/feed", app.Middleware.RequireUser(app.SocialHandler.HandleGetFeed))
+
+		r.Get("/tags", app.Middleware.RequireUser(app.TagHandler.HandleListTags))
+		r.Post("/tags/retag", app.Middleware.RequireUser(app.TagHandler.HandleRetag))
+
+		r.Get("/exercises", app.Middleware.RequireUser(app.ExerciseHandler.HandleListExercises))
+		r.Post("/exercises", app.Middleware.RequireUser(app.ExerciseHandler.HandleCreateExercise))
+		r.Get("/exercises/{id}", app.Middleware.RequireUser(app.ExerciseHandler.HandleGetExerciseByID))
+		r.Delete("/exercises/{id}", app.Middleware.RequireUser(app.ExerciseHandler.HandleDeleteExerciseByID))
 	})
 
 	r.Post("/users", app.UserHandler.HandleRegisterUser)
+	r.Get("/shared/{token}", app.ShareHandler.HandleGetSharedWorkout)
 
 	r.Post("/tokens/authetication", app.TokenHandler.HandleCreateToken)
 
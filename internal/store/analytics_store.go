@@ -0,0 +1,207 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	BucketDay   = "day"
+	BucketWeek  = "week"
+	BucketMonth = "month"
+
+	FormulaEpley   = "epley"
+	FormulaBrzycki = "brzycki"
+)
+
+var ErrInvalidAnalyticsQuery = errors.New("invalid analytics query")
+
+type AnalyticsQuery struct {
+	UserID  int64
+	From    time.Time
+	To      time.Time
+	Bucket  string
+	Formula string
+}
+
+type AnalyticsPoint struct {
+	Period   string  `json:"period"`
+	Workouts int     `json:"workouts"`
+	Volume   int     `json:"volume"`
+	Tonnage  float64 `json:"tonnage"`
+}
+
+type OneRepMaxPoint struct {
+	Period string  `json:"period"`
+	Value  float64 `json:"value"`
+}
+
+// Analytics holds time-bucketed training totals. Volume counts reps,
+// tonnage is reps x weight; warm-up sets count towards neither.
+type Analytics struct {
+	Bucket       string                      `json:"bucket"`
+	Formula      string                      `json:"formula"`
+	WeightUnit   string                      `json:"weight_unit"`
+	Series       []AnalyticsPoint            `json:"series"`
+	EstimatedMax map[string][]OneRepMaxPoint `json:"estimated_1rm"`
+}
+
+// ConvertWeights expresses tonnage and estimated maxes in unit.
+func (a *Analytics) ConvertWeights(unit string) {
+	for i := range a.Series {
+		a.Series[i].Tonnage = FromKilograms(a.Series[i].Tonnage, unit)
+	}
+	for _, points := range a.EstimatedMax {
+		for i := range points {
+			points[i].Value = FromKilograms(points[i].Value, unit)
+		}
+	}
+	a.WeightUnit = unit
+}
+
+type PostgresAnalyticsStore struct {
+	db *sql.DB
+}
+
+func NewPostgresAnalyticsStore(db *sql.DB) *PostgresAnalyticsStore {
+	return &PostgresAnalyticsStore{db: db}
+}
+
+type AnalyticsStore interface {
+	GetAnalytics(query AnalyticsQuery) (*Analytics, error)
+}
+
+// performedSetsQuery yields one row per performed set group: a logged
+// non-warm-up set, or the aggregate sets x reps x weight of a strength entry
+// that has no set log, repeated for each round of its superset or circuit.
+const performedSetsQuery = `
+	SELECT w.id AS workout_id,
+		date_trunc($4, w.created_at) AS period,
+		COALESCE(LOWER(e.name), LOWER(TRIM(we.exercise_name))) AS exercise,
+		CASE WHEN ws.id IS NULL THEN we.sets * COALESCE(we.group_rounds, 1) ELSE 1 END AS sets,
+		COALESCE(ws.reps, we.reps, 0) AS reps,
+		COALESCE(ws.weight, we.weight, 0) AS weight
+	FROM workouts w
+	INNER JOIN workout_entries we ON we.workout_id = w.id
+	LEFT JOIN exercises e ON e.id = we.exercise_id
+	LEFT JOIN workout_sets ws ON ws.entry_id = we.id
+	WHERE w.user_id = $1 AND w.created_at >= $2 AND w.created_at < $3 AND w.deleted_at IS NULL
+	AND we.entry_type = 'strength'
+	AND (ws.id IS NULL OR ws.set_type <> 'warmup')
+`
+
+func (pg *PostgresAnalyticsStore) GetAnalytics(q AnalyticsQuery) (*Analytics, error) {
+	switch q.Bucket {
+	case BucketDay, BucketWeek, BucketMonth:
+	default:
+		return nil, ErrInvalidAnalyticsQuery
+	}
+	switch q.Formula {
+	case FormulaEpley, FormulaBrzycki:
+	default:
+		return nil, ErrInvalidAnalyticsQuery
+	}
+
+	analytics := &Analytics{
+		Bucket:       q.Bucket,
+		Formula:      q.Formula,
+		WeightUnit:   UnitKilograms,
+		Series:       []AnalyticsPoint{},
+		EstimatedMax: map[string][]OneRepMaxPoint{},
+	}
+
+	frequencyQuery := `
+	SELECT date_trunc($4, created_at) AS period, COUNT(*)
+	FROM workouts
+	WHERE user_id = $1 AND created_at >= $2 AND created_at < $3 AND deleted_at IS NULL
+	GROUP BY period
+	ORDER BY period
+	`
+	rows, err := pg.db.Query(frequencyQuery, q.UserID, q.From, q.To, q.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byPeriod := map[string]int{}
+	for rows.Next() {
+		var period time.Time
+		var point AnalyticsPoint
+		err = rows.Scan(&period, &point.Workouts)
+		if err != nil {
+			return nil, err
+		}
+		point.Period = period.Format(dateLayout)
+		byPeriod[point.Period] = len(analytics.Series)
+		analytics.Series = append(analytics.Series, point)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	volumeQuery := `
+	WITH performed AS (` + performedSetsQuery + `)
+	SELECT period, SUM(sets * reps), SUM(sets * reps * weight)
+	FROM performed
+	GROUP BY period
+	`
+	volumeRows, err := pg.db.Query(volumeQuery, q.UserID, q.From, q.To, q.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	defer volumeRows.Close()
+
+	for volumeRows.Next() {
+		var period time.Time
+		var volume int
+		var tonnage float64
+		err = volumeRows.Scan(&period, &volume, &tonnage)
+		if err != nil {
+			return nil, err
+		}
+		if i, ok := byPeriod[period.Format(dateLayout)]; ok {
+			analytics.Series[i].Volume = volume
+			analytics.Series[i].Tonnage = tonnage
+		}
+	}
+	if err = volumeRows.Err(); err != nil {
+		return nil, err
+	}
+
+	maxQuery := `
+	WITH performed AS (` + performedSetsQuery + `)
+	SELECT exercise, period, MAX(
+		CASE
+			WHEN reps = 1 THEN weight
+			WHEN $5 = 'brzycki' THEN weight * 36 / (37 - reps)
+			ELSE weight * (1 + reps / 30.0)
+		END
+	)
+	FROM performed
+	WHERE weight > 0 AND reps > 0 AND ($5 <> 'brzycki' OR reps < 37)
+	GROUP BY exercise, period
+	ORDER BY exercise, period
+	`
+	maxRows, err := pg.db.Query(maxQuery, q.UserID, q.From, q.To, q.Bucket, q.Formula)
+	if err != nil {
+		return nil, err
+	}
+	defer maxRows.Close()
+
+	for maxRows.Next() {
+		var exercise string
+		var period time.Time
+		var value float64
+		err = maxRows.Scan(&exercise, &period, &value)
+		if err != nil {
+			return nil, err
+		}
+		analytics.EstimatedMax[exercise] = append(analytics.EstimatedMax[exercise], OneRepMaxPoint{
+			Period: period.Format(dateLayout),
+			Value:  value,
+		})
+	}
+
+	return analytics, maxRows.Err()
+}
@@ -0,0 +1,40 @@
+package store
+
+import "github.com/mhdph/go-start/internal/calories"
+
+// EstimateCalories records the MET-based estimate for the workout and,
+// unless the owner reported their own figure, uses the estimate as
+// CaloriesBurned. bodyWeight is in kilograms and nil when unknown. Workout
+// durations are in minutes, entry durations in seconds; a timed entry
+// holds its duration once per set.
+func (w *Workout) EstimateCalories(bodyWeight *float64) {
+	segments := make([]calories.Segment, 0, len(w.Entries))
+	for _, entry := range w.Entries {
+		segment := calories.Segment{Exercise: entry.ExerciesName}
+		if entry.Duration != nil {
+			seconds := *entry.Duration * entry.GroupRounds()
+			entryType := entry.Type
+			if entryType == "" {
+				entryType = entry.inferEntryType()
+			}
+			if entryType == EntryTypeTimed && entry.Sets > 1 {
+				seconds *= entry.Sets
+			}
+			segment.Minutes = float64(seconds) / 60
+		}
+		segments = append(segments, segment)
+	}
+
+	weight := calories.DefaultBodyWeight
+	if bodyWeight != nil {
+		weight = *bodyWeight
+	}
+
+	estimated := calories.EstimateWorkout(segments, weight, float64(w.Duration))
+	w.CaloriesEstimated = &estimated
+	if w.CaloriesReported != nil {
+		w.CaloriesBurned = *w.CaloriesReported
+	} else {
+		w.CaloriesBurned = estimated
+	}
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/mhdph/go-start/internal/calories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateCalories(t *testing.T) {
+	seconds := 1200
+	workout := &Workout{Duration: 60, Entries: []WorkoutEntry{{ExerciesName: "Treadmill run", Duration: &seconds}}}
+
+	workout.EstimateCalories(nil)
+	require.NotNil(t, workout.CaloriesEstimated)
+	want := calories.EstimateWorkout([]calories.Segment{{Exercise: "Treadmill run", Minutes: 20}}, calories.DefaultBodyWeight, 60)
+	assert.Equal(t, want, *workout.CaloriesEstimated)
+	assert.Equal(t, want, workout.CaloriesBurned)
+
+	// a heavier owner burns more, and a reported figure wins over the estimate
+	weight := 90.0
+	reported := 250
+	workout.CaloriesReported = &reported
+	workout.EstimateCalories(&weight)
+	assert.Greater(t, *workout.CaloriesEstimated, want)
+	assert.Equal(t, 250, workout.CaloriesBurned)
+
+	// a timed entry holds its duration once per set
+	hold := 60
+	workout = &Workout{Duration: 10, Entries: []WorkoutEntry{{ExerciesName: "Plank", Type: EntryTypeTimed, Sets: 3, Duration: &hold}}}
+	workout.EstimateCalories(nil)
+	want = calories.EstimateWorkout([]calories.Segment{{Exercise: "Plank", Minutes: 3}}, calories.DefaultBodyWeight, 10)
+	assert.Equal(t, want, *workout.CaloriesEstimated)
+}
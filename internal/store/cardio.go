@@ -0,0 +1,89 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/paulmach/orb"
+)
+
+// Route is a GeoJSON LineString geometry.
+type Route struct {
+	Type        string         `json:"type"`
+	Coordinates orb.LineString `json:"coordinates"`
+}
+
+func NewRoute(line orb.LineString) *Route {
+	return &Route{Type: "LineString", Coordinates: line}
+}
+
+type Split struct {
+	Kilometer       int     `json:"kilometer"`
+	DistanceMeters  float64 `json:"distance_meters"`
+	DurationSeconds int     `json:"duration_seconds"`
+}
+
+// CardioSummary is what a GPS recording adds to a workout.
+type CardioSummary struct {
+	DurationSeconds     int     `json:"duration_seconds"`
+	DistanceMeters      float64 `json:"distance_meters"`
+	ElevationGain       float64 `json:"elevation_gain"`
+	AvgPaceSecondsPerKm float64 `json:"avg_pace_seconds_per_km"`
+	AvgSpeedKmh         float64 `json:"avg_speed_kmh"`
+	AvgHeartRate        *int    `json:"avg_heart_rate"`
+	Splits              []Split `json:"splits"`
+	Route               *Route  `json:"route"`
+}
+
+func insertCardio(tx *sql.Tx, workout *Workout) error {
+	if workout.Cardio == nil {
+		return nil
+	}
+	cardio := workout.Cardio
+
+	splits, err := json.Marshal(cardio.Splits)
+	if err != nil {
+		return err
+	}
+	route, err := json.Marshal(cardio.Route)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO workout_routes (workout_id, duration_seconds, distance_meters, elevation_gain, avg_pace_seconds_per_km, avg_speed_kmh, avg_heart_rate, splits, route)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err = tx.Exec(query, workout.ID, cardio.DurationSeconds, cardio.DistanceMeters, cardio.ElevationGain, cardio.AvgPaceSecondsPerKm, cardio.AvgSpeedKmh, cardio.AvgHeartRate, splits, route)
+	return err
+}
+
+func loadCardio(q rowQueryer, workout *Workout) error {
+	cardio := &CardioSummary{}
+	var splits, route []byte
+
+	query := `
+	SELECT duration_seconds, distance_meters, elevation_gain, avg_pace_seconds_per_km, avg_speed_kmh, avg_heart_rate, splits, route
+	FROM workout_routes
+	WHERE workout_id = $1
+	`
+	err := q.QueryRow(query, workout.ID).Scan(&cardio.DurationSeconds, &cardio.DistanceMeters, &cardio.ElevationGain, &cardio.AvgPaceSecondsPerKm, &cardio.AvgSpeedKmh, &cardio.AvgHeartRate, &splits, &route)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	err = json.Unmarshal(splits, &cardio.Splits)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(route, &cardio.Route)
+	if err != nil {
+		return err
+	}
+
+	workout.Cardio = cardio
+	return nil
+}
@@ -0,0 +1,271 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const maxCommentLength = 2000
+
+// Reactions are limited to a fixed palette so counts stay meaningful.
+var Reactions = []string{"👍", "🔥", "💪", "👏", "❤️", "🎉"}
+
+var (
+	ErrInvalidComment  = errors.New("comment must be between 1 and 2000 characters")
+	ErrInvalidParent   = errors.New("parent comment not found on this workout")
+	ErrInvalidReaction = errors.New("unsupported reaction")
+)
+
+type Comment struct {
+	ID        int         `json:"id"`
+	WorkoutID int         `json:"workout_id"`
+	ParentID  *int        `json:"parent_id"`
+	Author    UserSummary `json:"author"`
+	Body      string      `json:"body"`
+	CreatedAt time.Time   `json:"created_at"`
+	Replies   []*Comment  `json:"replies"`
+}
+
+type Reaction struct {
+	User      UserSummary `json:"user"`
+	Emoji     string      `json:"emoji"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type PostgresCommentStore struct {
+	db *sql.DB
+}
+
+func NewPostgresCommentStore(db *sql.DB) *PostgresCommentStore {
+	return &PostgresCommentStore{db: db}
+}
+
+type CommentStore interface {
+	CreateComment(*Comment) (*Comment, error)
+	GetCommentByID(id int64) (*Comment, error)
+	GetComments(workoutID int64) ([]*Comment, error)
+	DeleteComment(id int64) error
+	AddReaction(workoutID int64, userID int, emoji string) error
+	RemoveReaction(workoutID int64, userID int, emoji string) error
+	GetReactions(workoutID int64) ([]Reaction, error)
+}
+
+func ValidReaction(emoji string) bool {
+	for _, reaction := range Reactions {
+		if emoji == reaction {
+			return true
+		}
+	}
+	return false
+}
+
+func (pg *PostgresCommentStore) CreateComment(comment *Comment) (*Comment, error) {
+	comment.Body = strings.TrimSpace(comment.Body)
+	if comment.Body == "" || utf8.RuneCountInString(comment.Body) > maxCommentLength {
+		return nil, ErrInvalidComment
+	}
+
+	// replies must stay on the workout their parent belongs to
+	query := `
+	INSERT INTO workout_comments (workout_id, user_id, parent_id, body)
+	SELECT $1, $2, $3, $4
+	WHERE $3::BIGINT IS NULL OR EXISTS (SELECT 1 FROM workout_comments WHERE id = $3 AND workout_id = $1)
+	RETURNING id, created_at, (SELECT username FROM users WHERE id = $2)
+	`
+	err := pg.db.QueryRow(query, comment.WorkoutID, comment.Author.ID, comment.ParentID, comment.Body).Scan(&comment.ID, &comment.CreatedAt, &comment.Author.Username)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidParent
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	comment.Replies = []*Comment{}
+	return comment, nil
+}
+
+func (pg *PostgresCommentStore) GetCommentByID(id int64) (*Comment, error) {
+	comment := &Comment{}
+	query := `
+	SELECT c.id, c.workout_id, c.parent_id, u.id, u.username, c.body, c.created_at
+	FROM workout_comments c
+	INNER JOIN users u ON u.id = c.user_id
+	WHERE c.id = $1
+	`
+	err := pg.db.QueryRow(query, id).Scan(&comment.ID, &comment.WorkoutID, &comment.ParentID, &comment.Author.ID, &comment.Author.Username, &comment.Body, &comment.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// GetComments returns the workout's comments as threads, oldest first.
+func (pg *PostgresCommentStore) GetComments(workoutID int64) ([]*Comment, error) {
+	query := `
+	SELECT c.id, c.workout_id, c.parent_id, u.id, u.username, c.body, c.created_at
+	FROM workout_comments c
+	INNER JOIN users u ON u.id = c.user_id
+	WHERE c.workout_id = $1
+	ORDER BY c.created_at, c.id
+	`
+	rows, err := pg.db.Query(query, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*Comment{}
+	for rows.Next() {
+		comment := &Comment{}
+		err = rows.Scan(&comment.ID, &comment.WorkoutID, &comment.ParentID, &comment.Author.ID, &comment.Author.Username, &comment.Body, &comment.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		comments = append(comments, comment)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return BuildCommentThreads(comments), nil
+}
+
+// BuildCommentThreads nests replies under their parents, keeping the
+// order of the input at every level.
+func BuildCommentThreads(comments []*Comment) []*Comment {
+	byID := make(map[int]*Comment, len(comments))
+	for _, comment := range comments {
+		comment.Replies = []*Comment{}
+		byID[comment.ID] = comment
+	}
+
+	threads := []*Comment{}
+	for _, comment := range comments {
+		if comment.ParentID != nil {
+			if parent, ok := byID[*comment.ParentID]; ok {
+				parent.Replies = append(parent.Replies, comment)
+				continue
+			}
+		}
+		threads = append(threads, comment)
+	}
+	return threads
+}
+
+// DeleteComment removes a comment and, through the foreign key, its replies.
+func (pg *PostgresCommentStore) DeleteComment(id int64) error {
+	result, err := pg.db.Exec(`DELETE FROM workout_comments WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (pg *PostgresCommentStore) AddReaction(workoutID int64, userID int, emoji string) error {
+	if !ValidReaction(emoji) {
+		return ErrInvalidReaction
+	}
+
+	query := `
+	INSERT INTO workout_reactions (workout_id, user_id, emoji)
+	VALUES ($1, $2, $3)
+	ON CONFLICT DO NOTHING
+	`
+	_, err := pg.db.Exec(query, workoutID, userID, emoji)
+	return err
+}
+
+func (pg *PostgresCommentStore) RemoveReaction(workoutID int64, userID int, emoji string) error {
+	result, err := pg.db.Exec(`DELETE FROM workout_reactions WHERE workout_id = $1 AND user_id = $2 AND emoji = $3`, workoutID, userID, emoji)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (pg *PostgresCommentStore) GetReactions(workoutID int64) ([]Reaction, error) {
+	query := `
+	SELECT u.id, u.username, r.emoji, r.created_at
+	FROM workout_reactions r
+	INNER JOIN users u ON u.id = r.user_id
+	WHERE r.workout_id = $1
+	ORDER BY r.created_at
+	`
+	rows, err := pg.db.Query(query, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := []Reaction{}
+	for rows.Next() {
+		var reaction Reaction
+		err = rows.Scan(&reaction.User.ID, &reaction.User.Username, &reaction.Emoji, &reaction.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, reaction)
+	}
+	return reactions, rows.Err()
+}
+
+// loadReactionCounts fills in the reaction counts of every workout with a
+// single query.
+func loadReactionCounts(q queryer, workouts []*Workout) error {
+	if len(workouts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(workouts))
+	byID := make(map[int]*Workout, len(workouts))
+	for _, workout := range workouts {
+		workout.Reactions = map[string]int{}
+		ids = append(ids, int64(workout.ID))
+		byID[workout.ID] = workout
+	}
+
+	query := `
+	SELECT workout_id, emoji, COUNT(*)
+	FROM workout_reactions
+	WHERE workout_id = ANY($1)
+	GROUP BY workout_id, emoji
+	`
+	rows, err := q.Query(query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workoutID, count int
+		var emoji string
+		err = rows.Scan(&workoutID, &emoji, &count)
+		if err != nil {
+			return err
+		}
+		byID[workoutID].Reactions[emoji] = count
+	}
+	return rows.Err()
+}
@@ -0,0 +1,34 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCommentThreads(t *testing.T) {
+	comments := []*Comment{
+		{ID: 1, Body: "nice"},
+		{ID: 2, Body: "thanks", ParentID: IntPtr(1)},
+		{ID: 3, Body: "new pr?"},
+		{ID: 4, Body: "yes", ParentID: IntPtr(2)},
+		{ID: 5, Body: "orphan", ParentID: IntPtr(99)},
+	}
+
+	threads := BuildCommentThreads(comments)
+	require.Len(t, threads, 3)
+	assert.Equal(t, []int{1, 3, 5}, []int{threads[0].ID, threads[1].ID, threads[2].ID})
+
+	require.Len(t, threads[0].Replies, 1)
+	assert.Equal(t, 2, threads[0].Replies[0].ID)
+	require.Len(t, threads[0].Replies[0].Replies, 1)
+	assert.Equal(t, 4, threads[0].Replies[0].Replies[0].ID)
+	assert.Empty(t, threads[1].Replies)
+}
+
+func TestValidReaction(t *testing.T) {
+	assert.True(t, ValidReaction("🔥"))
+	assert.False(t, ValidReaction("lol"))
+	assert.False(t, ValidReaction(""))
+}
@@ -2,9 +2,11 @@ package store
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"io/fs"
 
+	"github.com/jackc/pgconn"
 	"github.com/pressly/goose/v3"
 
 	_ "github.com/jackc/pgx/v4/stdlib"
@@ -44,3 +46,10 @@ func Migrate(db *sql.DB, dir string) error {
 
 	return nil
 }
+
+// isUniqueViolation reports whether Postgres rejected a write because it
+// would duplicate a unique key.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	GroupTypeSuperset = "superset"
+	GroupTypeCircuit  = "circuit"
+
+	maxGroupLabelLength = 20
+)
+
+// EntryGroup ties consecutive entries into a superset or circuit that is
+// gone through Rounds times, resting RestSeconds between rounds. Entries
+// of a group share its Label. A grouped entry without a set log describes
+// a single round, so its work counts Rounds times; a set log records every
+// set actually done across all rounds.
+type EntryGroup struct {
+	Label       string `json:"label"`
+	Type        string `json:"type"`
+	Rounds      int    `json:"rounds"`
+	RestSeconds *int   `json:"rest_seconds,omitempty"`
+}
+
+// GroupRounds is how many times the entry's own work was done: the rounds
+// of its group, or 1 for an ungrouped entry or one with a set log.
+func (e *WorkoutEntry) GroupRounds() int {
+	if e.Group == nil || len(e.SetLog) > 0 {
+		return 1
+	}
+	return e.Group.Rounds
+}
+
+// groupColumns flattens the group into its nullable workout_entries columns.
+func (e *WorkoutEntry) groupColumns() (label, groupType *string, rounds, restSeconds *int) {
+	if e.Group == nil {
+		return nil, nil, nil, nil
+	}
+	return &e.Group.Label, &e.Group.Type, &e.Group.Rounds, e.Group.RestSeconds
+}
+
+// validateGroup normalizes the entry's group, defaulting to a single-round
+// superset.
+func (e *WorkoutEntry) validateGroup() error {
+	if e.Group == nil {
+		return nil
+	}
+	g := e.Group
+
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: entry %q: group %s", ErrInvalidWorkoutEntry, e.ExerciesName, reason)
+	}
+
+	g.Label = strings.TrimSpace(g.Label)
+	if g.Label == "" || len(g.Label) > maxGroupLabelLength {
+		return invalid(fmt.Sprintf("label must be 1 to %d characters", maxGroupLabelLength))
+	}
+	if g.Type == "" {
+		g.Type = GroupTypeSuperset
+	}
+	if g.Type != GroupTypeSuperset && g.Type != GroupTypeCircuit {
+		return invalid("type must be superset or circuit")
+	}
+	if g.Rounds == 0 {
+		g.Rounds = 1
+	}
+	if g.Rounds < 0 {
+		return invalid("rounds must be at least 1")
+	}
+	if g.RestSeconds != nil && *g.RestSeconds < 0 {
+		return invalid("rest cannot be negative")
+	}
+	return nil
+}
+
+// validateGroups checks that the entries of each group agree on its type,
+// rounds and rest, and follow one another in the workout.
+func validateGroups(entries []WorkoutEntry) error {
+	ordered := make([]*WorkoutEntry, len(entries))
+	for i := range entries {
+		ordered[i] = &entries[i]
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].OrderIndex < ordered[j].OrderIndex
+	})
+
+	groups := map[string]*EntryGroup{}
+	previous := ""
+	for _, entry := range ordered {
+		label := ""
+		if entry.Group != nil {
+			label = entry.Group.Label
+		}
+		if label == "" || label == previous {
+			previous = label
+			continue
+		}
+		previous = label
+
+		if _, seen := groups[label]; seen {
+			return fmt.Errorf("%w: entries of group %q must be consecutive", ErrInvalidWorkoutEntry, label)
+		}
+		groups[label] = entry.Group
+	}
+
+	for _, entry := range entries {
+		if entry.Group == nil {
+			continue
+		}
+		group := groups[entry.Group.Label]
+		if entry.Group.Type != group.Type || entry.Group.Rounds != group.Rounds || !equalIntPtr(entry.Group.RestSeconds, group.RestSeconds) {
+			return fmt.Errorf("%w: entries of group %q disagree on its type, rounds or rest", ErrInvalidWorkoutEntry, entry.Group.Label)
+		}
+	}
+	return nil
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
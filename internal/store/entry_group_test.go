@@ -0,0 +1,53 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateGroup(t *testing.T) {
+	entry := WorkoutEntry{ExerciesName: "Burpees", Group: &EntryGroup{Label: " A "}}
+	require.NoError(t, entry.validateGroup())
+	assert.Equal(t, EntryGroup{Label: "A", Type: GroupTypeSuperset, Rounds: 1}, *entry.Group)
+
+	for _, group := range []EntryGroup{
+		{Label: ""},
+		{Label: "A", Type: "emom"},
+		{Label: "A", Rounds: -1},
+		{Label: "A", RestSeconds: IntPtr(-5)},
+	} {
+		entry := WorkoutEntry{Group: &group}
+		assert.ErrorIs(t, entry.validateGroup(), ErrInvalidWorkoutEntry, "%+v", group)
+	}
+}
+
+func TestValidateGroups(t *testing.T) {
+	circuit := func() *EntryGroup { return &EntryGroup{Label: "A", Type: GroupTypeCircuit, Rounds: 3} }
+
+	entries := []WorkoutEntry{
+		{OrderIndex: 0},
+		{OrderIndex: 1, Group: circuit()},
+		{OrderIndex: 2, Group: circuit()},
+		{OrderIndex: 3, Group: &EntryGroup{Label: "B", Type: GroupTypeSuperset, Rounds: 2}},
+	}
+	assert.NoError(t, validateGroups(entries))
+
+	entries[0].OrderIndex, entries[1].OrderIndex = 1, 0
+	assert.ErrorIs(t, validateGroups(entries), ErrInvalidWorkoutEntry, "group split by another entry")
+
+	entries[0].OrderIndex, entries[1].OrderIndex = 0, 1
+	entries[2].Group.Rounds = 4
+	assert.ErrorIs(t, validateGroups(entries), ErrInvalidWorkoutEntry, "rounds disagree")
+}
+
+func TestGroupedEntrySets(t *testing.T) {
+	entry := WorkoutEntry{Type: EntryTypeStrength, Sets: 1, Reps: IntPtr(10), Weight: FloatPtr(20), Group: &EntryGroup{Label: "A", Rounds: 3}}
+	sets := entrySets(entry)
+	require.Len(t, sets, 1)
+	assert.Equal(t, 3, sets[0].count)
+
+	entry.SetLog = []WorkoutSet{{Type: SetTypeWorking, Reps: IntPtr(10), Weight: FloatPtr(20)}}
+	assert.Equal(t, 1, entry.GroupRounds())
+}
@@ -0,0 +1,257 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+)
+
+var ErrInvalidEntryOrder = errors.New("order must list every entry of the workout exactly once")
+
+// PostgresEntryStore edits the entries of a workout one at a time instead
+// of rewriting them all. Every change bumps the workout's version and
+// records a revision, like UpdateWorkout does.
+type PostgresEntryStore struct {
+	db *sql.DB
+}
+
+func NewPostgresEntryStore(db *sql.DB) *PostgresEntryStore {
+	return &PostgresEntryStore{db: db}
+}
+
+// EntryStore methods take the workout as it was loaded, check it is still
+// at workout.Version and leave it updated on success. Callers are expected
+// to have checked that the workout belongs to actorID.
+type EntryStore interface {
+	CreateEntry(workout *Workout, entry *WorkoutEntry, actorID int) error
+	UpdateEntry(workout *Workout, entry *WorkoutEntry, actorID int) error
+	DeleteEntry(workout *Workout, entryID int, actorID int) error
+	ReorderEntries(workout *Workout, entryIDs []int, actorID int) error
+}
+
+// CreateEntry appends the entry to the end of the workout.
+func (pg *PostgresEntryStore) CreateEntry(workout *Workout, entry *WorkoutEntry, actorID int) error {
+	return pg.changeEntries(workout, actorID, func(tx *sql.Tx) error {
+		err := resolveEntry(tx, workout.UserID, entry)
+		if err != nil {
+			return err
+		}
+
+		entry.OrderIndex = 0
+		for _, existing := range workout.Entries {
+			if existing.OrderIndex >= entry.OrderIndex {
+				entry.OrderIndex = existing.OrderIndex + 1
+			}
+		}
+
+		err = insertEntry(tx, workout.ID, entry)
+		if err != nil {
+			return err
+		}
+		workout.Entries = append(workout.Entries, *entry)
+		return nil
+	})
+}
+
+// UpdateEntry replaces the entry with entry.ID, set log included, keeping
+// its position. It returns sql.ErrNoRows if the workout has no such entry.
+func (pg *PostgresEntryStore) UpdateEntry(workout *Workout, entry *WorkoutEntry, actorID int) error {
+	i := entryIndex(workout, entry.ID)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+
+	return pg.changeEntries(workout, actorID, func(tx *sql.Tx) error {
+		err := resolveEntry(tx, workout.UserID, entry)
+		if err != nil {
+			return err
+		}
+		entry.OrderIndex = workout.Entries[i].OrderIndex
+		err = entry.prepare()
+		if err != nil {
+			return err
+		}
+
+		groupLabel, groupType, groupRounds, groupRest := entry.groupColumns()
+		query := `
+		UPDATE workout_entries
+		SET exercise_id = $1, exercise_name = $2, entry_type = $3, sets = $4, reps = $5, duration = $6, weight = $7, entered_unit = $8,
+			distance = $9, avg_heart_rate = $10, rounds = $11, work_seconds = $12, rest_seconds = $13, notes = $14,
+			group_label = $15, group_type = $16, group_rounds = $17, group_rest_seconds = $18
+		WHERE id = $19 AND workout_id = $20
+		`
+		_, err = tx.Exec(query, entry.ExerciseID, entry.ExerciesName, entry.Type, entry.Sets, entry.Reps, entry.Duration, entry.Weight, entry.EnteredUnit,
+			entry.Distance, entry.AvgHeartRate, entry.Rounds, entry.WorkSeconds, entry.RestSeconds, entry.Notes,
+			groupLabel, groupType, groupRounds, groupRest, entry.ID, workout.ID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM workout_sets WHERE entry_id = $1`, entry.ID)
+		if err != nil {
+			return err
+		}
+		err = insertSets(tx, entry)
+		if err != nil {
+			return err
+		}
+
+		workout.Entries[i] = *entry
+		return nil
+	})
+}
+
+// DeleteEntry removes the entry and closes the gap it leaves in the order.
+// It returns sql.ErrNoRows if the workout has no such entry.
+func (pg *PostgresEntryStore) DeleteEntry(workout *Workout, entryID int, actorID int) error {
+	i := entryIndex(workout, entryID)
+	if i < 0 {
+		return sql.ErrNoRows
+	}
+
+	return pg.changeEntries(workout, actorID, func(tx *sql.Tx) error {
+		removed := workout.Entries[i]
+		_, err := tx.Exec(`DELETE FROM workout_entries WHERE id = $1 AND workout_id = $2`, entryID, workout.ID)
+		if err != nil {
+			return err
+		}
+
+		query := `UPDATE workout_entries SET order_index = order_index - 1 WHERE workout_id = $1 AND order_index > $2`
+		_, err = tx.Exec(query, workout.ID, removed.OrderIndex)
+		if err != nil {
+			return err
+		}
+
+		workout.Entries = append(workout.Entries[:i], workout.Entries[i+1:]...)
+		for j := range workout.Entries {
+			if workout.Entries[j].OrderIndex > removed.OrderIndex {
+				workout.Entries[j].OrderIndex--
+			}
+		}
+		return nil
+	})
+}
+
+// ReorderEntries rewrites order_index so the entries follow entryIDs, which
+// must name every entry of the workout exactly once.
+func (pg *PostgresEntryStore) ReorderEntries(workout *Workout, entryIDs []int, actorID int) error {
+	if len(entryIDs) != len(workout.Entries) {
+		return ErrInvalidEntryOrder
+	}
+	byID := make(map[int]WorkoutEntry, len(workout.Entries))
+	for _, entry := range workout.Entries {
+		byID[entry.ID] = entry
+	}
+	reordered := make([]WorkoutEntry, 0, len(entryIDs))
+	ids := make([]int64, 0, len(entryIDs))
+	for i, id := range entryIDs {
+		entry, ok := byID[id]
+		if !ok {
+			return ErrInvalidEntryOrder
+		}
+		delete(byID, id)
+		entry.OrderIndex = i
+		reordered = append(reordered, entry)
+		ids = append(ids, int64(id))
+	}
+
+	return pg.changeEntries(workout, actorID, func(tx *sql.Tx) error {
+		query := `
+		UPDATE workout_entries AS e
+		SET order_index = o.position - 1
+		FROM unnest($1::bigint[]) WITH ORDINALITY AS o(id, position)
+		WHERE e.id = o.id AND e.workout_id = $2
+		`
+		_, err := tx.Exec(query, ids, workout.ID)
+		if err != nil {
+			return err
+		}
+		workout.Entries = reordered
+		return nil
+	})
+}
+
+// changeEntries runs change in a transaction that first claims the next
+// version of the workout, then records the calorie estimate, the records
+// and the revision that follow from the changed entries.
+func (pg *PostgresEntryStore) changeEntries(workout *Workout, actorID int, change func(tx *sql.Tx) error) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = saveBaseline(tx, workout.ID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE workouts SET version = version + 1
+	WHERE id = $1 AND deleted_at IS NULL AND version = $2
+	RETURNING version
+	`
+	var version int
+	err = tx.QueryRow(query, workout.ID, workout.Version).Scan(&version)
+	if err == sql.ErrNoRows {
+		return versionError(tx, workout.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = change(tx)
+	if err != nil {
+		return err
+	}
+	err = validateGroups(workout.Entries)
+	if err != nil {
+		return err
+	}
+	workout.Version = version
+
+	// the estimate follows the entries, with the owner's body weight
+	var bodyWeight *float64
+	err = tx.QueryRow(`SELECT body_weight FROM users WHERE id = $1`, workout.UserID).Scan(&bodyWeight)
+	if err != nil {
+		return err
+	}
+	workout.EstimateCalories(bodyWeight)
+	query = `UPDATE workouts SET calories_burned = $1, calories_estimated = $2 WHERE id = $3`
+	_, err = tx.Exec(query, workout.CaloriesBurned, workout.CaloriesEstimated, workout.ID)
+	if err != nil {
+		return err
+	}
+
+	workout.NewRecords, err = refreshRecords(tx, workout.UserID, workout.ID)
+	if err != nil {
+		return err
+	}
+
+	err = saveRevision(tx, workout, &actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// resolveEntry links a single entry to the exercise catalog.
+func resolveEntry(q queryer, userID int, entry *WorkoutEntry) error {
+	entries := []WorkoutEntry{*entry}
+	err := resolveExercises(q, userID, entries)
+	if err != nil {
+		return err
+	}
+	*entry = entries[0]
+	return nil
+}
+
+func entryIndex(workout *Workout, entryID int) int {
+	for i := range workout.Entries {
+		if workout.Entries[i].ID == entryID {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,19 @@
+package store
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntryChangesCheckWorkoutFirst(t *testing.T) {
+	pg := NewPostgresEntryStore(nil)
+	workout := &Workout{ID: 1, Entries: []WorkoutEntry{{ID: 10}, {ID: 11}, {ID: 12}}}
+
+	for _, ids := range [][]int{{10, 11}, {10, 11, 13}, {10, 11, 11}} {
+		assert.ErrorIs(t, pg.ReorderEntries(workout, ids, 1), ErrInvalidEntryOrder, "%v", ids)
+	}
+	assert.ErrorIs(t, pg.UpdateEntry(workout, &WorkoutEntry{ID: 99}, 1), sql.ErrNoRows)
+	assert.ErrorIs(t, pg.DeleteEntry(workout, 99, 1), sql.ErrNoRows)
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+const (
+	EntryTypeStrength = "strength"
+	EntryTypeCardio   = "cardio"
+	EntryTypeTimed    = "timed"
+	EntryTypeInterval = "interval"
+)
+
+var ErrInvalidWorkoutEntry = errors.New("invalid workout entry")
+
+// inferEntryType picks a type for entries sent without one, so clients
+// written before typed entries keep working: a distance means cardio,
+// rounds mean intervals and a duration without reps is a timed hold.
+func (e *WorkoutEntry) inferEntryType() string {
+	switch {
+	case e.Distance != nil:
+		return EntryTypeCardio
+	case e.Rounds != nil:
+		return EntryTypeInterval
+	case e.Duration != nil && e.Reps == nil && len(e.SetLog) == 0:
+		return EntryTypeTimed
+	default:
+		return EntryTypeStrength
+	}
+}
+
+// validate checks the fields the entry's type needs, rejects the ones it
+// does not use and fills in the derived ones.
+func (e *WorkoutEntry) validate() error {
+	if e.Type == "" {
+		e.Type = e.inferEntryType()
+	}
+
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s entry %q: %s", ErrInvalidWorkoutEntry, e.Type, e.ExerciesName, fmt.Sprintf(format, args...))
+	}
+
+	if e.Duration != nil && *e.Duration < 0 {
+		return invalid("duration cannot be negative")
+	}
+	if e.Weight != nil && *e.Weight < 0 {
+		return invalid("weight cannot be negative")
+	}
+
+	switch e.Type {
+	case EntryTypeStrength:
+		if e.Distance != nil || e.AvgHeartRate != nil || e.Rounds != nil || e.WorkSeconds != nil || e.RestSeconds != nil {
+			return invalid("only sets, reps, weight and duration apply")
+		}
+		if e.Reps == nil || *e.Reps < 1 {
+			return invalid("reps must be at least 1")
+		}
+		if e.Sets < 1 {
+			return invalid("sets must be at least 1")
+		}
+
+	case EntryTypeCardio:
+		if e.Reps != nil || e.Weight != nil || e.Rounds != nil || e.WorkSeconds != nil || e.RestSeconds != nil || len(e.SetLog) > 0 {
+			return invalid("only duration, distance and heart rate apply")
+		}
+		if e.Distance == nil && e.Duration == nil {
+			return invalid("distance or duration is required")
+		}
+		if e.Distance != nil && *e.Distance < 0 {
+			return invalid("distance cannot be negative")
+		}
+		if e.AvgHeartRate != nil && (*e.AvgHeartRate < 20 || *e.AvgHeartRate > 250) {
+			return invalid("avg_heart_rate must be between 20 and 250")
+		}
+		e.Sets = 1
+
+	case EntryTypeTimed:
+		if e.Reps != nil || e.Distance != nil || e.AvgHeartRate != nil || e.Rounds != nil || e.WorkSeconds != nil || e.RestSeconds != nil || len(e.SetLog) > 0 {
+			return invalid("only sets, duration and weight apply")
+		}
+		if e.Duration == nil || *e.Duration < 1 {
+			return invalid("duration must be at least 1 second")
+		}
+		if e.Sets < 1 {
+			e.Sets = 1
+		}
+
+	case EntryTypeInterval:
+		if e.Reps != nil || e.Distance != nil || e.Weight != nil || len(e.SetLog) > 0 {
+			return invalid("only rounds, work_seconds, rest_seconds and heart rate apply")
+		}
+		if e.Rounds == nil || *e.Rounds < 1 {
+			return invalid("rounds must be at least 1")
+		}
+		if e.WorkSeconds == nil || *e.WorkSeconds < 1 {
+			return invalid("work_seconds must be at least 1")
+		}
+		if e.RestSeconds != nil && *e.RestSeconds < 0 {
+			return invalid("rest_seconds cannot be negative")
+		}
+		if e.AvgHeartRate != nil && (*e.AvgHeartRate < 20 || *e.AvgHeartRate > 250) {
+			return invalid("avg_heart_rate must be between 20 and 250")
+		}
+		rest := 0
+		if e.RestSeconds != nil {
+			rest = *e.RestSeconds
+		}
+		duration := *e.Rounds**e.WorkSeconds + (*e.Rounds-1)*rest
+		e.Duration = &duration
+		e.Sets = *e.Rounds
+
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidWorkoutEntry, e.Type)
+	}
+
+	e.derivePace()
+	return nil
+}
+
+// derivePace sets the average pace of a cardio entry in seconds per km.
+func (e *WorkoutEntry) derivePace() {
+	e.Pace = nil
+	if e.Type != EntryTypeCardio || e.Distance == nil || e.Duration == nil || *e.Distance <= 0 {
+		return
+	}
+	pace := math.Round(float64(*e.Duration) / (*e.Distance / 1000))
+	e.Pace = &pace
+}
@@ -0,0 +1,81 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntryValidateInfersType(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry WorkoutEntry
+		want  string
+	}{
+		{"reps", WorkoutEntry{Sets: 3, Reps: IntPtr(5)}, EntryTypeStrength},
+		{"distance", WorkoutEntry{Distance: FloatPtr(5000)}, EntryTypeCardio},
+		{"hold", WorkoutEntry{Duration: IntPtr(60)}, EntryTypeTimed},
+		{"rounds", WorkoutEntry{Rounds: IntPtr(8), WorkSeconds: IntPtr(20)}, EntryTypeInterval},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.entry.validate())
+			assert.Equal(t, tt.want, tt.entry.Type)
+		})
+	}
+}
+
+func TestEntryValidateRejects(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry WorkoutEntry
+	}{
+		{"strength without reps", WorkoutEntry{Type: EntryTypeStrength, Sets: 3}},
+		{"strength with distance", WorkoutEntry{Type: EntryTypeStrength, Sets: 1, Reps: IntPtr(5), Distance: FloatPtr(100)}},
+		{"cardio with reps", WorkoutEntry{Type: EntryTypeCardio, Duration: IntPtr(600), Reps: IntPtr(5)}},
+		{"cardio without distance or duration", WorkoutEntry{Type: EntryTypeCardio}},
+		{"cardio heart rate", WorkoutEntry{Type: EntryTypeCardio, Duration: IntPtr(600), AvgHeartRate: IntPtr(400)}},
+		{"timed without duration", WorkoutEntry{Type: EntryTypeTimed, Sets: 1}},
+		{"interval without work", WorkoutEntry{Type: EntryTypeInterval, Rounds: IntPtr(8)}},
+		{"unknown type", WorkoutEntry{Type: "yoga"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.entry.validate(), ErrInvalidWorkoutEntry)
+		})
+	}
+}
+
+func TestEntryValidateDerivesFields(t *testing.T) {
+	run := WorkoutEntry{Type: EntryTypeCardio, Distance: FloatPtr(5000), Duration: IntPtr(1500)}
+	require.NoError(t, run.validate())
+	assert.Equal(t, 1, run.Sets)
+	require.NotNil(t, run.Pace)
+	assert.Equal(t, 300.0, *run.Pace)
+
+	tabata := WorkoutEntry{Type: EntryTypeInterval, Rounds: IntPtr(8), WorkSeconds: IntPtr(20), RestSeconds: IntPtr(10)}
+	require.NoError(t, tabata.validate())
+	assert.Equal(t, 8, tabata.Sets)
+	assert.Equal(t, 230, *tabata.Duration)
+}
+
+func TestEntryJSONOmitsUnusedFields(t *testing.T) {
+	run := WorkoutEntry{ExerciesName: "Running", Type: EntryTypeCardio, Sets: 1, Distance: FloatPtr(5000), Duration: IntPtr(1500)}
+	data, err := json.Marshal(run)
+	require.NoError(t, err)
+
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &fields))
+	assert.Equal(t, "cardio", fields["type"])
+	assert.Equal(t, 5000.0, fields["distance"])
+	assert.NotContains(t, fields, "reps")
+	assert.NotContains(t, fields, "rounds")
+
+	var decoded WorkoutEntry
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, run, decoded)
+}
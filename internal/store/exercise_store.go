@@ -0,0 +1,322 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jackc/pgtype"
+)
+
+// minExerciseSimilarity is how close every word of a free-text name has to
+// be to a catalog name or alias before it is mapped to that exercise.
+const minExerciseSimilarity = 0.75
+
+var (
+	ErrUnknownExercise   = errors.New("unknown exercise")
+	ErrDuplicateExercise = errors.New("an exercise with this name already exists")
+)
+
+// Exercise is either part of the shared catalog (UserID is nil) or a custom
+// exercise only visible to its owner.
+type Exercise struct {
+	ID               int       `json:"id"`
+	UserID           *int      `json:"user_id"`
+	Name             string    `json:"name"`
+	Aliases          []string  `json:"aliases"`
+	PrimaryMuscles   []string  `json:"primary_muscles"`
+	SecondaryMuscles []string  `json:"secondary_muscles"`
+	Equipment        string    `json:"equipment"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type PostgresExerciseStore struct {
+	db *sql.DB
+}
+
+func NewPostgresExerciseStore(db *sql.DB) *PostgresExerciseStore {
+	return &PostgresExerciseStore{db: db}
+}
+
+type ExerciseStore interface {
+	CreateExercise(*Exercise) (*Exercise, error)
+	GetExerciseByID(id int64) (*Exercise, error)
+	GetExercisesForUser(userID int64) ([]*Exercise, error)
+	DeleteExercise(id int64) error
+}
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func (pg *PostgresExerciseStore) CreateExercise(exercise *Exercise) (*Exercise, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	if exercise.PrimaryMuscles == nil {
+		exercise.PrimaryMuscles = []string{}
+	}
+	if exercise.SecondaryMuscles == nil {
+		exercise.SecondaryMuscles = []string{}
+	}
+
+	query := `
+	INSERT INTO exercises (user_id, name, primary_muscles, secondary_muscles, equipment)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at
+	`
+	err = tx.QueryRow(query, exercise.UserID, exercise.Name, exercise.PrimaryMuscles, exercise.SecondaryMuscles, exercise.Equipment).Scan(&exercise.ID, &exercise.CreatedAt)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicateExercise
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, alias := range exercise.Aliases {
+		_, err = tx.Exec(`INSERT INTO exercise_aliases (exercise_id, alias) VALUES ($1, $2) ON CONFLICT DO NOTHING`, exercise.ID, normalizeExerciseName(alias))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return exercise, nil
+}
+
+func (pg *PostgresExerciseStore) GetExerciseByID(id int64) (*Exercise, error) {
+	exercises, err := loadExercises(pg.db, `WHERE e.id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(exercises) == 0 {
+		return nil, nil
+	}
+	return exercises[0], nil
+}
+
+// GetExercisesForUser returns the shared catalog plus the user's own
+// custom exercises.
+func (pg *PostgresExerciseStore) GetExercisesForUser(userID int64) ([]*Exercise, error) {
+	return loadExercises(pg.db, `WHERE e.user_id IS NULL OR e.user_id = $1`, userID)
+}
+
+func (pg *PostgresExerciseStore) DeleteExercise(id int64) error {
+	result, err := pg.db.Exec(`DELETE FROM exercises WHERE id = $1 AND user_id IS NOT NULL`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func loadExercises(q queryer, where string, args ...interface{}) ([]*Exercise, error) {
+	query := `
+	SELECT e.id, e.user_id, e.name, e.primary_muscles, e.secondary_muscles, e.equipment, e.created_at,
+		COALESCE(ARRAY_AGG(a.alias ORDER BY a.alias) FILTER (WHERE a.alias IS NOT NULL), '{}')
+	FROM exercises e
+	LEFT JOIN exercise_aliases a ON a.exercise_id = e.id
+	` + where + `
+	GROUP BY e.id
+	ORDER BY e.name
+	`
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exercises := []*Exercise{}
+	for rows.Next() {
+		exercise := &Exercise{}
+		var primary, secondary, aliases pgtype.TextArray
+		err = rows.Scan(&exercise.ID, &exercise.UserID, &exercise.Name, &primary, &secondary, &exercise.Equipment, &exercise.CreatedAt, &aliases)
+		if err != nil {
+			return nil, err
+		}
+		if err = primary.AssignTo(&exercise.PrimaryMuscles); err != nil {
+			return nil, err
+		}
+		if err = secondary.AssignTo(&exercise.SecondaryMuscles); err != nil {
+			return nil, err
+		}
+		if err = aliases.AssignTo(&exercise.Aliases); err != nil {
+			return nil, err
+		}
+		exercises = append(exercises, exercise)
+	}
+	return exercises, rows.Err()
+}
+
+// resolveExercises fills in ExerciseID for entries that only carry a name
+// and checks that explicit IDs are visible to the user.
+func resolveExercises(q queryer, userID int, entries []WorkoutEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	catalog, err := loadExercises(q, `WHERE e.user_id IS NULL OR e.user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[int]*Exercise, len(catalog))
+	for _, exercise := range catalog {
+		byID[exercise.ID] = exercise
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+		if entry.ExerciseID != nil {
+			exercise, ok := byID[*entry.ExerciseID]
+			if !ok {
+				return ErrUnknownExercise
+			}
+			entry.canonicalName = exercise.Name
+			if entry.ExerciesName == "" {
+				entry.ExerciesName = exercise.Name
+			}
+			continue
+		}
+
+		exercise := MatchExercise(entry.ExerciesName, catalog)
+		if exercise != nil {
+			exerciseID := exercise.ID
+			entry.ExerciseID = &exerciseID
+			entry.canonicalName = exercise.Name
+		}
+	}
+	return nil
+}
+
+// MatchExercise finds the catalog exercise a free-text name refers to. An
+// exact name or alias wins; otherwise a name with the same words, allowing
+// small typos, is used. Qualifier words such as "incline" or "front" have
+// to match exactly so that near misses between different lifts stay
+// unlinked. Custom exercises win ties over the shared catalog.
+func MatchExercise(name string, catalog []*Exercise) *Exercise {
+	needle := exerciseMatchKey(name)
+	if needle == "" {
+		return nil
+	}
+	needleWords := strings.Fields(needle)
+
+	var best *Exercise
+	bestScore := 0.0
+	for _, exercise := range catalog {
+		candidates := append([]string{exercise.Name}, exercise.Aliases...)
+		for _, candidate := range candidates {
+			score := wordSimilarity(needleWords, strings.Fields(exerciseMatchKey(candidate)))
+			if score > bestScore || (score == bestScore && best != nil && best.UserID == nil && exercise.UserID != nil) {
+				best, bestScore = exercise, score
+			}
+		}
+	}
+
+	if bestScore < minExerciseSimilarity {
+		return nil
+	}
+	return best
+}
+
+// exerciseQualifiers are the words that tell otherwise similar lifts apart.
+// A typo in one of them is treated as a different exercise.
+var exerciseQualifiers = map[string]bool{
+	"back": true, "front": true, "hack": true, "sumo": true, "box": true,
+	"incline": true, "decline": true, "flat": true, "close": true, "wide": true,
+	"reverse": true, "romanian": true, "stiff": true, "split": true, "goblet": true,
+	"seated": true, "standing": true, "lying": true, "overhead": true,
+	"barbell": true, "dumbbell": true, "cable": true, "machine": true, "smith": true,
+	"kettlebell": true, "single": true, "one": true, "leg": true, "arm": true,
+	"hip": true, "calf": true, "lat": true, "rear": true, "lateral": true,
+}
+
+// wordSimilarity compares two names word by word and returns the lowest
+// similarity of any word pair, or 0 if the names cannot refer to the same
+// exercise. Names that only differ in spacing, like "pullup" and
+// "pull up", are identical.
+func wordSimilarity(a, b []string) float64 {
+	if strings.Join(a, "") == strings.Join(b, "") {
+		return 1
+	}
+	if len(a) != len(b) {
+		return 0
+	}
+
+	score := 1.0
+	for i := range a {
+		if a[i] == b[i] {
+			continue
+		}
+		if exerciseQualifiers[a[i]] || exerciseQualifiers[b[i]] {
+			return 0
+		}
+		score = min(score, similarity(a[i], b[i]))
+	}
+	return score
+}
+
+// exerciseMatchKey lowercases the name and drops punctuation so that
+// "Pull-Up", "pull up" and "pullup " compare close together.
+func exerciseMatchKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// similarity is 1 minus the normalized Levenshtein distance of a and b.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return 1 - float64(previous[len(rb)])/float64(longest)
+}
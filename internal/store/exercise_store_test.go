@@ -0,0 +1,53 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchExercise(t *testing.T) {
+	userID := 7
+	catalog := []*Exercise{
+		{ID: 1, Name: "Bench Press", Aliases: []string{"bench", "bp"}},
+		{ID: 2, Name: "Pull-up", Aliases: []string{"pullup"}},
+		{ID: 3, Name: "Deadlift"},
+		{ID: 4, UserID: &userID, Name: "Bench"},
+		{ID: 5, Name: "Back Squat", Aliases: []string{"squat"}},
+		{ID: 6, Name: "Incline Bench Press"},
+	}
+
+	tests := []struct {
+		name   string
+		input  string
+		wantID int
+	}{
+		{name: "exact name", input: "Bench Press", wantID: 1},
+		{name: "case and spacing", input: "  bench   PRESS ", wantID: 1},
+		{name: "alias", input: "BP", wantID: 1},
+		{name: "punctuation", input: "pull up", wantID: 2},
+		{name: "typo", input: "Deadlfit", wantID: 3},
+		{name: "custom exercise wins ties", input: "bench", wantID: 4},
+		{name: "missing space", input: "benchpress", wantID: 1},
+		{name: "typo in a qualified name", input: "Back Sqat", wantID: 5},
+		{name: "no match", input: "Zercher carry", wantID: 0},
+		{name: "hack squat is not back squat", input: "Hack Squat", wantID: 0},
+		{name: "front squat is not back squat", input: "Front Squat", wantID: 0},
+		{name: "decline is not incline", input: "Decline Bench Press", wantID: 0},
+		{name: "extra words", input: "Paused Bench Press", wantID: 0},
+		{name: "empty", input: "", wantID: 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			exercise := MatchExercise(test.input, catalog)
+			if test.wantID == 0 {
+				assert.Nil(t, exercise)
+				return
+			}
+			require.NotNil(t, exercise)
+			assert.Equal(t, test.wantID, exercise.ID)
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	GoalAchieved   = "achieved"
+	GoalInProgress = "in_progress"
+	GoalBehind     = "behind"
+	GoalMissed     = "missed"
+
+	DefaultGraceDays = 1
+	MaxGraceDays     = 6
+)
+
+// GoalProgress is where a goal stands. For periodic goals Current counts
+// the current period only and Streak is how many periods in a row, since
+// the goal was set, met the target, the current one included once it has.
+type GoalProgress struct {
+	Current     float64    `json:"current"`
+	Target      float64    `json:"target"`
+	Percent     float64    `json:"percent"`
+	Status      string     `json:"status"`
+	PeriodStart *time.Time `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time `json:"period_end,omitempty"`
+	Streak      int        `json:"streak"`
+}
+
+// Streak counts consecutive training days. Up to GraceDays rest days
+// between two workouts do not break it, and a streak stays current until
+// more than GraceDays have passed since the last workout.
+type Streak struct {
+	Current     int        `json:"current"`
+	Longest     int        `json:"longest"`
+	GraceDays   int        `json:"grace_days"`
+	LastWorkout *time.Time `json:"last_workout,omitempty"`
+}
+
+// ComputeProgress measures the goal against the user's activity as of now.
+func ComputeProgress(goal *Goal, activity *GoalActivity, now time.Time) *GoalProgress {
+	progress := &GoalProgress{Target: goal.Target}
+
+	if goal.Type == GoalTypeLift {
+		progress.Current = activity.BestLifts[normalizeExerciseName(goal.ExerciseName)]
+		switch {
+		case progress.Current >= goal.Target:
+			progress.Status = GoalAchieved
+		case goal.Deadline != nil && truncateDay(now).After(truncateDay(*goal.Deadline)):
+			progress.Status = GoalMissed
+		default:
+			progress.Status = GoalInProgress
+		}
+		progress.Percent = percent(progress.Current, goal.Target)
+		return progress
+	}
+
+	totals := map[time.Time]float64{}
+	for _, day := range activity.Days {
+		value := float64(day.Workouts)
+		if goal.Type == GoalTypeVolume {
+			value = day.Tonnage
+		}
+		totals[periodStart(day.Date, goal.Period)] += value
+	}
+
+	start := periodStart(now, goal.Period)
+	end := nextPeriod(start, goal.Period)
+	progress.PeriodStart = &start
+	progress.PeriodEnd = &end
+	progress.Current = totals[start]
+	progress.Percent = percent(progress.Current, goal.Target)
+
+	first := periodStart(goal.CreatedAt, goal.Period)
+	for period := previousPeriod(start, goal.Period); !period.Before(first); period = previousPeriod(period, goal.Period) {
+		if totals[period] < goal.Target {
+			break
+		}
+		progress.Streak++
+	}
+
+	switch {
+	case progress.Current >= goal.Target:
+		progress.Status = GoalAchieved
+		progress.Streak++
+	case isBehind(goal, progress.Current, now, start, end):
+		progress.Status = GoalBehind
+	default:
+		progress.Status = GoalInProgress
+	}
+	return progress
+}
+
+// isBehind reports whether a periodic goal is off pace: a frequency goal
+// needs more workouts than days are left, a volume goal trails the share
+// of its target that the elapsed days call for.
+func isBehind(goal *Goal, current float64, now, start, end time.Time) bool {
+	days := end.Sub(start).Hours() / 24
+	elapsed := math.Floor(truncateDay(now).Sub(start).Hours()/24) + 1
+	if goal.Type == GoalTypeFrequency {
+		return goal.Target-current > days-elapsed+1
+	}
+	return current < goal.Target*(elapsed-1)/days
+}
+
+// ComputeStreak follows the training days, given in ascending order, with
+// graceDays of rest allowed between workouts.
+func ComputeStreak(days []TrainingDay, graceDays int, now time.Time) Streak {
+	streak := Streak{GraceDays: graceDays}
+	if len(days) == 0 {
+		return streak
+	}
+
+	run := 0
+	var last time.Time
+	for _, day := range days {
+		date := truncateDay(day.Date)
+		if run > 0 && daysBetween(last, date)-1 > graceDays {
+			run = 0
+		}
+		if run == 0 || date.After(last) {
+			run++
+		}
+		last = date
+		if run > streak.Longest {
+			streak.Longest = run
+		}
+	}
+
+	streak.LastWorkout = &last
+	if daysBetween(last, truncateDay(now))-1 <= graceDays {
+		streak.Current = run
+	}
+	return streak
+}
+
+func percent(current, target float64) float64 {
+	if target <= 0 {
+		return 0
+	}
+	return math.Round(math.Min(current/target, 1)*1000) / 10
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
+
+// periodStart is the Monday of t's week or the first of its month, in UTC,
+// matching date_trunc.
+func periodStart(t time.Time, period string) time.Time {
+	day := truncateDay(t)
+	if period == BucketMonth {
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+}
+
+func nextPeriod(start time.Time, period string) time.Time {
+	if period == BucketMonth {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 7)
+}
+
+func previousPeriod(start time.Time, period string) time.Time {
+	if period == BucketMonth {
+		return start.AddDate(0, -1, 0)
+	}
+	return start.AddDate(0, 0, -7)
+}
@@ -0,0 +1,144 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func trainingDays(dates ...string) []TrainingDay {
+	days := make([]TrainingDay, 0, len(dates))
+	for _, date := range dates {
+		days = append(days, TrainingDay{Date: day(date), Workouts: 1, Tonnage: 2500})
+	}
+	return days
+}
+
+func TestComputeFrequencyProgress(t *testing.T) {
+	// Thursday 2025-03-13, goal set three weeks earlier
+	now := day("2025-03-13").Add(18 * time.Hour)
+	goal := &Goal{Type: GoalTypeFrequency, Target: 3, Period: BucketWeek, CreatedAt: day("2025-02-18")}
+	activity := &GoalActivity{Days: trainingDays(
+		"2025-02-03", "2025-02-04", "2025-02-05", // before the goal, not counted
+		"2025-02-18", "2025-02-20", "2025-02-21",
+		"2025-02-24", "2025-02-26", "2025-03-01",
+		"2025-03-03", "2025-03-05", "2025-03-07",
+		"2025-03-10",
+	)}
+
+	progress := ComputeProgress(goal, activity, now)
+	assert.Equal(t, 1.0, progress.Current)
+	assert.Equal(t, 33.3, progress.Percent)
+	assert.Equal(t, day("2025-03-10"), *progress.PeriodStart)
+	assert.Equal(t, day("2025-03-17"), *progress.PeriodEnd)
+	assert.Equal(t, GoalInProgress, progress.Status)
+	assert.Equal(t, 3, progress.Streak)
+
+	// with two workouts to go and only Sunday left it is behind
+	progress = ComputeProgress(goal, activity, day("2025-03-16"))
+	assert.Equal(t, GoalBehind, progress.Status)
+
+	activity.Days = append(activity.Days, trainingDays("2025-03-11", "2025-03-12")...)
+	progress = ComputeProgress(goal, activity, now)
+	assert.Equal(t, GoalAchieved, progress.Status)
+	assert.Equal(t, 4, progress.Streak)
+}
+
+func TestComputeVolumeProgress(t *testing.T) {
+	goal := &Goal{Type: GoalTypeVolume, Target: 10000, Period: BucketMonth, CreatedAt: day("2025-03-01")}
+	activity := &GoalActivity{Days: trainingDays("2025-03-02", "2025-03-09")}
+
+	progress := ComputeProgress(goal, activity, day("2025-03-10"))
+	assert.Equal(t, 5000.0, progress.Current)
+	assert.Equal(t, 50.0, progress.Percent)
+	assert.Equal(t, GoalInProgress, progress.Status)
+
+	progress = ComputeProgress(goal, activity, day("2025-03-25"))
+	assert.Equal(t, GoalBehind, progress.Status)
+}
+
+func TestComputeLiftProgress(t *testing.T) {
+	deadline := day("2025-03-31")
+	goal := &Goal{Type: GoalTypeLift, Target: 100, ExerciseName: "Bench  Press", Deadline: &deadline}
+	activity := &GoalActivity{BestLifts: map[string]float64{"bench press": 92.5}}
+
+	progress := ComputeProgress(goal, activity, day("2025-03-31"))
+	assert.Equal(t, 92.5, progress.Current)
+	assert.Equal(t, GoalInProgress, progress.Status)
+	assert.Nil(t, progress.PeriodStart)
+
+	assert.Equal(t, GoalMissed, ComputeProgress(goal, activity, day("2025-04-01")).Status)
+
+	activity.BestLifts["bench press"] = 100
+	assert.Equal(t, GoalAchieved, ComputeProgress(goal, activity, day("2025-04-01")).Status)
+}
+
+func TestGoalResolveExercise(t *testing.T) {
+	catalog := []*Exercise{
+		{ID: 1, Name: "Bench Press", Aliases: []string{"bench"}},
+		{ID: 2, Name: "Back Squat", Aliases: []string{"squat"}},
+	}
+
+	goal := &Goal{Type: GoalTypeLift, Target: 140, ExerciseName: "Squat"}
+	goal.resolveExercise(catalog)
+	assert.Equal(t, "Back Squat", goal.ExerciseName)
+
+	activity := &GoalActivity{BestLifts: map[string]float64{"back squat": 120}}
+	assert.Equal(t, 120.0, ComputeProgress(goal, activity, day("2025-03-01")).Current)
+
+	goal = &Goal{Type: GoalTypeLift, Target: 60, ExerciseName: "Zercher Carry"}
+	goal.resolveExercise(catalog)
+	assert.Equal(t, "Zercher Carry", goal.ExerciseName)
+}
+
+func TestComputeStreak(t *testing.T) {
+	days := trainingDays("2025-03-01", "2025-03-02", "2025-03-03", "2025-03-07", "2025-03-09", "2025-03-10")
+
+	streak := ComputeStreak(days, 1, day("2025-03-11"))
+	assert.Equal(t, 3, streak.Current)
+	assert.Equal(t, 3, streak.Longest)
+	require.NotNil(t, streak.LastWorkout)
+	assert.Equal(t, day("2025-03-10"), *streak.LastWorkout)
+
+	streak = ComputeStreak(days, 3, day("2025-03-11"))
+	assert.Equal(t, 6, streak.Current)
+
+	streak = ComputeStreak(days, 0, day("2025-03-12"))
+	assert.Equal(t, 0, streak.Current)
+	assert.Equal(t, 3, streak.Longest)
+
+	assert.Equal(t, Streak{GraceDays: 1}, ComputeStreak(nil, 1, day("2025-03-12")))
+}
+
+func TestGoalValidate(t *testing.T) {
+	valid := []Goal{
+		{Type: GoalTypeFrequency, Target: 4, Period: BucketWeek},
+		{Type: GoalTypeVolume, Target: 10000, Period: BucketMonth},
+		{Type: GoalTypeLift, Target: 100, ExerciseName: "Bench press"},
+	}
+	for _, goal := range valid {
+		assert.NoError(t, goal.validate(), "%+v", goal)
+	}
+
+	invalid := []Goal{
+		{Type: GoalTypeFrequency, Target: 0, Period: BucketWeek},
+		{Type: GoalTypeFrequency, Target: 4, Period: BucketDay},
+		{Type: GoalTypeVolume, Target: 1, Period: BucketWeek, ExerciseName: "Squat"},
+		{Type: GoalTypeLift, Target: 100},
+		{Type: GoalTypeLift, Target: 100, ExerciseName: "Squat", Period: BucketWeek},
+		{Type: "distance", Target: 5},
+	}
+	for _, goal := range invalid {
+		assert.ErrorIs(t, goal.validate(), ErrInvalidGoal, "%+v", goal)
+	}
+}
@@ -0,0 +1,317 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	GoalTypeFrequency = "frequency"
+	GoalTypeVolume    = "volume"
+	GoalTypeLift      = "lift"
+
+	// StreakLookback is how far back training streaks are followed.
+	StreakLookback = 366 * 24 * time.Hour
+)
+
+var ErrInvalidGoal = errors.New("invalid goal")
+
+// Goal is something a user is training towards: a number of workouts or
+// a tonnage (kg) per week or month, or a weight (kg) to lift on an
+// exercise, optionally by a deadline. Progress is filled in on read.
+type Goal struct {
+	ID           int           `json:"id"`
+	UserID       int           `json:"user_id"`
+	Type         string        `json:"type"`
+	Target       float64       `json:"target"`
+	WeightUnit   string        `json:"weight_unit,omitempty"`
+	ExerciseName string        `json:"exercise_name,omitempty"`
+	Period       string        `json:"period,omitempty"`
+	Deadline     *time.Time    `json:"deadline,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	Progress     *GoalProgress `json:"progress,omitempty"`
+}
+
+// TrainingDay sums up one day of logged workouts.
+type TrainingDay struct {
+	Date     time.Time
+	Workouts int
+	Tonnage  float64
+}
+
+// GoalActivity is the training history goal progress is computed from:
+// training days in ascending order and the best weight (kg) lifted on each
+// goal exercise, keyed by normalized name.
+type GoalActivity struct {
+	Days      []TrainingDay
+	BestLifts map[string]float64
+}
+
+type PostgresGoalStore struct {
+	db *sql.DB
+}
+
+func NewPostgresGoalStore(db *sql.DB) *PostgresGoalStore {
+	return &PostgresGoalStore{db: db}
+}
+
+type GoalStore interface {
+	CreateGoal(goal *Goal) (*Goal, error)
+	GetGoals(userID int64) ([]*Goal, error)
+	DeleteGoal(id, userID int64) error
+	GetGoalActivity(userID int64, goals []*Goal, now time.Time) (*GoalActivity, error)
+}
+
+// validate checks the fields the goal's type needs and clears the ones it
+// does not use.
+func (g *Goal) validate() error {
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s", ErrInvalidGoal, reason)
+	}
+
+	if g.Target <= 0 {
+		return invalid("target must be positive")
+	}
+	g.ExerciseName = strings.Join(strings.Fields(g.ExerciseName), " ")
+
+	switch g.Type {
+	case GoalTypeFrequency, GoalTypeVolume:
+		if g.Period != BucketWeek && g.Period != BucketMonth {
+			return invalid("period must be week or month")
+		}
+		if g.ExerciseName != "" {
+			return invalid("only lift goals name an exercise")
+		}
+	case GoalTypeLift:
+		if g.ExerciseName == "" {
+			return invalid("exercise_name is required")
+		}
+		if g.Period != "" {
+			return invalid("lift goals have a deadline, not a period")
+		}
+	default:
+		return invalid("type must be frequency, volume or lift")
+	}
+	return nil
+}
+
+// resolveExercise replaces the goal's exercise name with the catalog name it
+// refers to, the name personal records are kept under. Names without a
+// catalog match are kept as typed.
+func (g *Goal) resolveExercise(catalog []*Exercise) {
+	exercise := MatchExercise(g.ExerciseName, catalog)
+	if exercise != nil {
+		g.ExerciseName = exercise.Name
+	}
+}
+
+func (pg *PostgresGoalStore) CreateGoal(goal *Goal) (*Goal, error) {
+	err := goal.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if goal.Type == GoalTypeLift {
+		catalog, err := loadExercises(pg.db, `WHERE e.user_id IS NULL OR e.user_id = $1`, goal.UserID)
+		if err != nil {
+			return nil, err
+		}
+		goal.resolveExercise(catalog)
+	}
+
+	var exerciseName, period *string
+	if goal.ExerciseName != "" {
+		exerciseName = &goal.ExerciseName
+	}
+	if goal.Period != "" {
+		period = &goal.Period
+	}
+
+	query := `
+	INSERT INTO goals (user_id, goal_type, target, exercise_name, period, deadline)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at
+	`
+	err = pg.db.QueryRow(query, goal.UserID, goal.Type, goal.Target, exerciseName, period, goal.Deadline).Scan(&goal.ID, &goal.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	goal.setWeightUnit()
+	return goal, nil
+}
+
+func (pg *PostgresGoalStore) GetGoals(userID int64) ([]*Goal, error) {
+	query := `
+	SELECT id, user_id, goal_type, target, exercise_name, period, deadline, created_at
+	FROM goals
+	WHERE user_id = $1
+	ORDER BY created_at, id
+	`
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []*Goal{}
+	for rows.Next() {
+		goal := &Goal{}
+		var exerciseName, period sql.NullString
+		err = rows.Scan(&goal.ID, &goal.UserID, &goal.Type, &goal.Target, &exerciseName, &period, &goal.Deadline, &goal.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		goal.ExerciseName = exerciseName.String
+		goal.Period = period.String
+		goal.setWeightUnit()
+		goals = append(goals, goal)
+	}
+	return goals, rows.Err()
+}
+
+func (pg *PostgresGoalStore) DeleteGoal(id, userID int64) error {
+	result, err := pg.db.Exec(`DELETE FROM goals WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetGoalActivity loads what goals need to compute their progress: the
+// user's training days since StreakLookback or the start of the first
+// goal's period, whichever is earlier, and their best lift on each goal
+// exercise.
+func (pg *PostgresGoalStore) GetGoalActivity(userID int64, goals []*Goal, now time.Time) (*GoalActivity, error) {
+	from := now.Add(-StreakLookback)
+	exercises := []string{}
+	for _, goal := range goals {
+		if goal.Period != "" {
+			if start := periodStart(goal.CreatedAt, goal.Period); start.Before(from) {
+				from = start
+			}
+		}
+		if goal.Type == GoalTypeLift {
+			exercises = append(exercises, normalizeExerciseName(goal.ExerciseName))
+		}
+	}
+	from = truncateDay(from)
+	to := truncateDay(now).AddDate(0, 0, 1)
+
+	activity := &GoalActivity{BestLifts: map[string]float64{}}
+	byDay := map[time.Time]*TrainingDay{}
+
+	query := `
+	SELECT date_trunc('day', created_at) AS day, COUNT(*)
+	FROM workouts
+	WHERE user_id = $1 AND created_at >= $2 AND created_at < $3 AND deleted_at IS NULL
+	GROUP BY day
+	ORDER BY day
+	`
+	rows, err := pg.db.Query(query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		day := TrainingDay{}
+		err = rows.Scan(&day.Date, &day.Workouts)
+		if err != nil {
+			return nil, err
+		}
+		day.Date = truncateDay(day.Date)
+		activity.Days = append(activity.Days, day)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	for i := range activity.Days {
+		byDay[activity.Days[i].Date] = &activity.Days[i]
+	}
+
+	tonnageQuery := `
+	WITH performed AS (` + performedSetsQuery + `)
+	SELECT period, SUM(sets * reps * weight)
+	FROM performed
+	GROUP BY period
+	`
+	tonnageRows, err := pg.db.Query(tonnageQuery, userID, from, to, BucketDay)
+	if err != nil {
+		return nil, err
+	}
+	defer tonnageRows.Close()
+
+	for tonnageRows.Next() {
+		var period time.Time
+		var tonnage float64
+		err = tonnageRows.Scan(&period, &tonnage)
+		if err != nil {
+			return nil, err
+		}
+		if day, ok := byDay[truncateDay(period)]; ok {
+			day.Tonnage = tonnage
+		}
+	}
+	if err = tonnageRows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(exercises) == 0 {
+		return activity, nil
+	}
+
+	liftQuery := `
+	SELECT exercise_name, MAX(value)
+	FROM personal_records
+	WHERE user_id = $1 AND record_type = $2 AND exercise_name = ANY($3)
+	GROUP BY exercise_name
+	`
+	liftRows, err := pg.db.Query(liftQuery, userID, RecordMaxWeight, exercises)
+	if err != nil {
+		return nil, err
+	}
+	defer liftRows.Close()
+
+	for liftRows.Next() {
+		var exercise string
+		var best float64
+		err = liftRows.Scan(&exercise, &best)
+		if err != nil {
+			return nil, err
+		}
+		activity.BestLifts[exercise] = best
+	}
+	return activity, liftRows.Err()
+}
+
+func (g *Goal) setWeightUnit() {
+	g.WeightUnit = ""
+	if g.Type != GoalTypeFrequency {
+		g.WeightUnit = UnitKilograms
+	}
+}
+
+// ConvertWeights expresses the target and progress of a lift or volume
+// goal in unit.
+func (g *Goal) ConvertWeights(unit string) {
+	if g.Type == GoalTypeFrequency {
+		return
+	}
+	g.Target = FromKilograms(g.Target, unit)
+	if g.Progress != nil {
+		g.Progress.Current = FromKilograms(g.Progress.Current, unit)
+		g.Progress.Target = FromKilograms(g.Progress.Target, unit)
+	}
+	g.WeightUnit = unit
+}
@@ -0,0 +1,325 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+type Program struct {
+	ID          int              `json:"id"`
+	UserID      int              `json:"user_id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	StartDate   time.Time        `json:"start_date"`
+	Weeks       int              `json:"weeks"`
+	Sessions    []ProgramSession `json:"sessions"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+// ProgramSession plans a template on a weekday. A nil Week repeats the
+// session every week of the program.
+type ProgramSession struct {
+	ID         int          `json:"id"`
+	TemplateID int          `json:"template_id"`
+	Title      string       `json:"title"`
+	Weekday    time.Weekday `json:"weekday"`
+	Week       *int         `json:"week"`
+}
+
+type PlannedSession struct {
+	ProgramID   int    `json:"program_id"`
+	ProgramName string `json:"program_name"`
+	SessionID   int    `json:"session_id"`
+	TemplateID  int    `json:"template_id"`
+	Title       string `json:"title"`
+	Week        int    `json:"week"`
+	WorkoutID   *int   `json:"workout_id"`
+}
+
+type CalendarDay struct {
+	Date      string           `json:"date"`
+	Planned   []PlannedSession `json:"planned"`
+	Completed []*Workout       `json:"completed"`
+	Unplanned []*Workout       `json:"unplanned"`
+}
+
+type PostgresProgramStore struct {
+	db *sql.DB
+}
+
+func NewPostgresProgramStore(db *sql.DB) *PostgresProgramStore {
+	return &PostgresProgramStore{db: db}
+}
+
+type ProgramStore interface {
+	CreateProgram(*Program) (*Program, error)
+	GetProgramByID(id int64) (*Program, error)
+	GetProgramsByUserID(userID int64) ([]*Program, error)
+	DeleteProgram(id int64) error
+	GetCalendar(userID int64, from, to time.Time) ([]CalendarDay, error)
+}
+
+func (pg *PostgresProgramStore) CreateProgram(program *Program) (*Program, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO programs (user_id, name, description, start_date, weeks)
+	VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at
+	`
+	err = tx.QueryRow(query, program.UserID, program.Name, program.Description, program.StartDate, program.Weeks).Scan(&program.ID, &program.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range program.Sessions {
+		session := &program.Sessions[i]
+		query = `
+		INSERT INTO program_sessions (program_id, template_id, weekday, week)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+		`
+		err = tx.QueryRow(query, program.ID, session.TemplateID, int(session.Weekday), session.Week).Scan(&session.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+func (pg *PostgresProgramStore) GetProgramByID(id int64) (*Program, error) {
+	program := &Program{}
+	query := `
+	SELECT id, user_id, name, description, start_date, weeks, created_at
+	FROM programs
+	WHERE id = $1
+	`
+	err := pg.db.QueryRow(query, id).Scan(&program.ID, &program.UserID, &program.Name, &program.Description, &program.StartDate, &program.Weeks, &program.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = pg.loadSessions([]*Program{program})
+	if err != nil {
+		return nil, err
+	}
+	return program, nil
+}
+
+func (pg *PostgresProgramStore) GetProgramsByUserID(userID int64) ([]*Program, error) {
+	query := `
+	SELECT id, user_id, name, description, start_date, weeks, created_at
+	FROM programs
+	WHERE user_id = $1
+	ORDER BY start_date DESC
+	`
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	programs := []*Program{}
+	for rows.Next() {
+		program := &Program{}
+		err = rows.Scan(&program.ID, &program.UserID, &program.Name, &program.Description, &program.StartDate, &program.Weeks, &program.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		programs = append(programs, program)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = pg.loadSessions(programs)
+	if err != nil {
+		return nil, err
+	}
+	return programs, nil
+}
+
+func (pg *PostgresProgramStore) DeleteProgram(id int64) error {
+	result, err := pg.db.Exec(`DELETE FROM programs WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetCalendar merges the sessions planned by the user's programs with the
+// workouts actually logged between from and to, both inclusive.
+func (pg *PostgresProgramStore) GetCalendar(userID int64, from, to time.Time) ([]CalendarDay, error) {
+	programs, err := pg.GetProgramsByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	SELECT id, user_id, title, description, duration, calories_burned, created_at
+	FROM workouts
+	WHERE user_id = $1 AND created_at >= $2 AND created_at < $3 AND deleted_at IS NULL
+	ORDER BY created_at
+	`
+	rows, err := pg.db.Query(query, userID, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workouts := []*Workout{}
+	for rows.Next() {
+		workout := &Workout{}
+		err = rows.Scan(&workout.ID, &workout.UserID, &workout.Title, &workout.Description, &workout.Duration, &workout.CaloriesBurned, &workout.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		workouts = append(workouts, workout)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return BuildCalendar(programs, workouts, from, to), nil
+}
+
+func (pg *PostgresProgramStore) loadSessions(programs []*Program) error {
+	if len(programs) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(programs))
+	byID := make(map[int]*Program, len(programs))
+	for _, program := range programs {
+		ids = append(ids, int64(program.ID))
+		byID[program.ID] = program
+	}
+
+	query := `
+	SELECT s.program_id, s.id, s.template_id, t.title, s.weekday, s.week
+	FROM program_sessions s
+	INNER JOIN workout_templates t ON t.id = s.template_id
+	WHERE s.program_id = ANY($1)
+	ORDER BY s.program_id, s.week NULLS FIRST, s.weekday
+	`
+	rows, err := pg.db.Query(query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var programID, weekday int
+		var session ProgramSession
+		err = rows.Scan(&programID, &session.ID, &session.TemplateID, &session.Title, &weekday, &session.Week)
+		if err != nil {
+			return err
+		}
+		session.Weekday = time.Weekday(weekday)
+		program := byID[programID]
+		program.Sessions = append(program.Sessions, session)
+	}
+
+	return rows.Err()
+}
+
+// BuildCalendar expands the programs into one CalendarDay per date between
+// from and to. A logged workout completes the first unmatched planned
+// session of the same day with the same title; anything else is unplanned.
+func BuildCalendar(programs []*Program, workouts []*Workout, from, to time.Time) []CalendarDay {
+	from = truncateDay(from)
+	to = truncateDay(to)
+
+	loggedByDate := map[string][]*Workout{}
+	for _, workout := range workouts {
+		date := workout.CreatedAt.Format(dateLayout)
+		loggedByDate[date] = append(loggedByDate[date], workout)
+	}
+
+	days := []CalendarDay{}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		calendarDay := CalendarDay{
+			Date:      day.Format(dateLayout),
+			Planned:   []PlannedSession{},
+			Completed: []*Workout{},
+			Unplanned: []*Workout{},
+		}
+
+		for _, program := range programs {
+			start := truncateDay(program.StartDate)
+			if day.Before(start) {
+				continue
+			}
+			week := int(day.Sub(start).Hours()/24)/7 + 1
+			if week > program.Weeks {
+				continue
+			}
+
+			for _, session := range program.Sessions {
+				if session.Weekday != day.Weekday() {
+					continue
+				}
+				if session.Week != nil && *session.Week != week {
+					continue
+				}
+				calendarDay.Planned = append(calendarDay.Planned, PlannedSession{
+					ProgramID:   program.ID,
+					ProgramName: program.Name,
+					SessionID:   session.ID,
+					TemplateID:  session.TemplateID,
+					Title:       session.Title,
+					Week:        week,
+				})
+			}
+		}
+
+		for _, workout := range loggedByDate[calendarDay.Date] {
+			matched := false
+			for i := range calendarDay.Planned {
+				planned := &calendarDay.Planned[i]
+				if planned.WorkoutID == nil && planned.Title == workout.Title {
+					workoutID := workout.ID
+					planned.WorkoutID = &workoutID
+					matched = true
+					break
+				}
+			}
+			if matched {
+				calendarDay.Completed = append(calendarDay.Completed, workout)
+			} else {
+				calendarDay.Unplanned = append(calendarDay.Unplanned, workout)
+			}
+		}
+
+		days = append(days, calendarDay)
+	}
+
+	return days
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCalendar(t *testing.T) {
+	// 2025-01-06 is a monday
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	program := &Program{
+		ID:        1,
+		Name:      "Strength block",
+		StartDate: start,
+		Weeks:     2,
+		Sessions: []ProgramSession{
+			{ID: 1, TemplateID: 10, Title: "Push day", Weekday: time.Monday},
+			{ID: 2, TemplateID: 11, Title: "Deload", Weekday: time.Friday, Week: IntPtr(2)},
+		},
+	}
+	workouts := []*Workout{
+		{ID: 100, Title: "Push day", CreatedAt: start.Add(18 * time.Hour)},
+		{ID: 101, Title: "Easy run", CreatedAt: start.Add(19 * time.Hour)},
+	}
+
+	days := BuildCalendar([]*Program{program}, workouts, start, start.AddDate(0, 0, 20))
+	require.Len(t, days, 21)
+
+	monday := days[0]
+	assert.Equal(t, "2025-01-06", monday.Date)
+	require.Len(t, monday.Planned, 1)
+	require.NotNil(t, monday.Planned[0].WorkoutID)
+	assert.Equal(t, 100, *monday.Planned[0].WorkoutID)
+	assert.Len(t, monday.Completed, 1)
+	require.Len(t, monday.Unplanned, 1)
+	assert.Equal(t, 101, monday.Unplanned[0].ID)
+
+	// the deload only happens on the friday of week two
+	assert.Empty(t, days[4].Planned)
+	require.Len(t, days[11].Planned, 1)
+	assert.Equal(t, "Deload", days[11].Planned[0].Title)
+	assert.Equal(t, 2, days[11].Planned[0].Week)
+	assert.Nil(t, days[11].Planned[0].WorkoutID)
+
+	// the program is over after two weeks
+	assert.Empty(t, days[14].Planned)
+}
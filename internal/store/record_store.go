@@ -0,0 +1,400 @@
+package store
+
+import (
+	"database/sql"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	RecordMaxWeight      = "max_weight"
+	RecordRepsAtWeight   = "reps_at_weight"
+	RecordEstimated1RM   = "estimated_1rm"
+	RecordMaxEntryVolume = "max_volume"
+)
+
+// PersonalRecord is the best value a user reached for one exercise and
+// record type. Weight is only part of the key for RecordRepsAtWeight.
+type PersonalRecord struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	ExerciseName string    `json:"exercise_name"`
+	RecordType   string    `json:"record_type"`
+	Weight       float64   `json:"weight"`
+	Reps         int       `json:"reps"`
+	Value        float64   `json:"value"`
+	WeightUnit   string    `json:"weight_unit"`
+	WorkoutID    *int      `json:"workout_id"`
+	AchievedAt   time.Time `json:"achieved_at"`
+}
+
+type PostgresRecordStore struct {
+	db *sql.DB
+}
+
+func NewPostgresRecordStore(db *sql.DB) *PostgresRecordStore {
+	return &PostgresRecordStore{db: db}
+}
+
+type RecordStore interface {
+	GetRecordsByUserID(userID int64) ([]*PersonalRecord, error)
+}
+
+func (pg *PostgresRecordStore) GetRecordsByUserID(userID int64) ([]*PersonalRecord, error) {
+	query := `
+	SELECT id, user_id, exercise_name, record_type, weight, reps, value, workout_id, achieved_at
+	FROM personal_records
+	WHERE user_id = $1
+	ORDER BY exercise_name, record_type, weight
+	`
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []*PersonalRecord{}
+	for rows.Next() {
+		record := &PersonalRecord{}
+		err = rows.Scan(&record.ID, &record.UserID, &record.ExerciseName, &record.RecordType, &record.Weight, &record.Reps, &record.Value, &record.WorkoutID, &record.AchievedAt)
+		if err != nil {
+			return nil, err
+		}
+		record.WeightUnit = UnitKilograms
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+// EstimateOneRepMax uses the Epley formula.
+func EstimateOneRepMax(weight float64, reps int) float64 {
+	if reps <= 0 {
+		return 0
+	}
+	if reps == 1 {
+		return weight
+	}
+	return weight * (1 + float64(reps)/30)
+}
+
+// CandidateRecords returns the best value of every record type reached by
+// the entries, one per exercise (and per weight for reps records).
+func CandidateRecords(entries []WorkoutEntry) []PersonalRecord {
+	type key struct {
+		exercise   string
+		recordType string
+		weight     float64
+	}
+	best := map[key]PersonalRecord{}
+	order := []key{}
+
+	consider := func(record PersonalRecord) {
+		k := key{record.ExerciseName, record.RecordType, record.Weight}
+		current, ok := best[k]
+		if !ok {
+			order = append(order, k)
+		}
+		if !ok || record.Value > current.Value {
+			best[k] = record
+		}
+	}
+
+	for _, entry := range entries {
+		exercise := normalizeExerciseName(entry.ExerciesName)
+		if entry.canonicalName != "" {
+			exercise = normalizeExerciseName(entry.canonicalName)
+		}
+		if exercise == "" {
+			continue
+		}
+
+		volume := 0.0
+		for _, set := range entrySets(entry) {
+			if set.weight <= 0 {
+				continue
+			}
+			consider(PersonalRecord{ExerciseName: exercise, RecordType: RecordMaxWeight, Reps: set.reps, Value: set.weight})
+			if set.reps <= 0 {
+				continue
+			}
+			consider(PersonalRecord{ExerciseName: exercise, RecordType: RecordRepsAtWeight, Weight: set.weight, Reps: set.reps, Value: float64(set.reps)})
+			consider(PersonalRecord{ExerciseName: exercise, RecordType: RecordEstimated1RM, Reps: set.reps, Value: EstimateOneRepMax(set.weight, set.reps)})
+			volume += set.weight * float64(set.reps*set.count)
+		}
+		if volume > 0 {
+			consider(PersonalRecord{ExerciseName: exercise, RecordType: RecordMaxEntryVolume, Value: volume})
+		}
+	}
+
+	records := make([]PersonalRecord, 0, len(order))
+	for _, k := range order {
+		records = append(records, best[k])
+	}
+	return records
+}
+
+type performedSet struct {
+	weight float64
+	reps   int
+	count  int
+}
+
+// entrySets lists what was lifted in an entry: its logged working sets, or
+// the aggregate sets x reps x weight for entries without a set log.
+func entrySets(entry WorkoutEntry) []performedSet {
+	if entry.Type != "" && entry.Type != EntryTypeStrength {
+		return nil
+	}
+	if len(entry.SetLog) > 0 {
+		sets := []performedSet{}
+		for _, set := range entry.SetLog {
+			if set.Type == SetTypeWarmup {
+				continue
+			}
+			sets = append(sets, performedSet{weight: setWeight(&set), reps: setReps(&set), count: 1})
+		}
+		return sets
+	}
+
+	if entry.Weight == nil {
+		return nil
+	}
+	set := performedSet{weight: *entry.Weight, count: entry.Sets}
+	if entry.Reps != nil {
+		set.reps = *entry.Reps
+	}
+	if set.count < 1 {
+		set.count = 1
+	}
+	set.count *= entry.GroupRounds()
+	return []performedSet{set}
+}
+
+// saveRecords upserts the records reached by a new workout and returns the
+// ones that beat the previous best. Records are dated by the workout, so
+// imported history keeps its own dates.
+func saveRecords(tx *sql.Tx, workout *Workout) ([]PersonalRecord, error) {
+	newRecords := []PersonalRecord{}
+
+	for _, record := range CandidateRecords(workout.Entries) {
+		query := `
+		INSERT INTO personal_records (user_id, exercise_name, record_type, weight, reps, value, workout_id, achieved_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, exercise_name, record_type, weight) DO UPDATE
+		SET reps = EXCLUDED.reps, value = EXCLUDED.value, workout_id = EXCLUDED.workout_id, achieved_at = EXCLUDED.achieved_at
+		WHERE personal_records.value < EXCLUDED.value
+		RETURNING id, achieved_at
+		`
+		record.UserID = workout.UserID
+		record.WeightUnit = UnitKilograms
+		workoutID := workout.ID
+		record.WorkoutID = &workoutID
+
+		err := tx.QueryRow(query, record.UserID, record.ExerciseName, record.RecordType, record.Weight, record.Reps, record.Value, workout.ID, workout.CreatedAt).Scan(&record.ID, &record.AchievedAt)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		newRecords = append(newRecords, record)
+	}
+
+	return newRecords, nil
+}
+
+// recordExerciseName is normalizeExerciseName in SQL, applied to the name
+// CandidateRecords keys an entry by.
+const recordExerciseName = `LOWER(REGEXP_REPLACE(TRIM(COALESCE(e.name, we.exercise_name)), '\s+', ' ', 'g'))`
+
+// recordKey identifies a record row.
+type recordKey struct {
+	exercise   string
+	recordType string
+	weight     float64
+}
+
+func keyOf(record PersonalRecord) recordKey {
+	return recordKey{record.ExerciseName, record.RecordType, math.Round(record.Weight*10000) / 10000}
+}
+
+// recordSource is one workout that records are computed from.
+type recordSource struct {
+	workoutID int
+	createdAt time.Time
+	entries   []WorkoutEntry
+}
+
+// bestRecords replays the workouts, oldest first, and keeps the best value
+// of every record. The first workout to reach a value holds the record.
+func bestRecords(userID int, sources []recordSource) []PersonalRecord {
+	best := map[recordKey]PersonalRecord{}
+	order := []recordKey{}
+	for _, source := range sources {
+		for _, record := range CandidateRecords(source.entries) {
+			k := keyOf(record)
+			current, ok := best[k]
+			if ok && record.Value <= current.Value {
+				continue
+			}
+			if !ok {
+				order = append(order, k)
+			}
+			workoutID := source.workoutID
+			record.UserID = userID
+			record.Weight = k.weight
+			record.WeightUnit = UnitKilograms
+			record.WorkoutID = &workoutID
+			record.AchievedAt = source.createdAt
+			best[k] = record
+		}
+	}
+
+	records := make([]PersonalRecord, 0, len(order))
+	for _, k := range order {
+		records = append(records, best[k])
+	}
+	return records
+}
+
+// refreshRecords recomputes the user's records on every exercise the
+// workout logs or holds a record on, from their workouts outside the
+// trash. Unlike saveRecords it can lower a record, so it follows a workout
+// that is edited down, deleted or restored. It returns the records the
+// workout holds afterwards that beat the previous best.
+func refreshRecords(tx *sql.Tx, userID, workoutID int) ([]PersonalRecord, error) {
+	query := `
+	SELECT exercise_name FROM personal_records WHERE workout_id = $1
+	UNION
+	SELECT ` + recordExerciseName + `
+	FROM workout_entries we
+	LEFT JOIN exercises e ON e.id = we.exercise_id
+	WHERE we.workout_id = $1
+	`
+	exercises := []string{}
+	rows, err := tx.Query(query, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var exercise string
+		err = rows.Scan(&exercise)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		exercises = append(exercises, exercise)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(exercises) == 0 {
+		return []PersonalRecord{}, nil
+	}
+
+	previous := map[recordKey]float64{}
+	rows, err = tx.Query(`SELECT exercise_name, record_type, weight, value FROM personal_records WHERE user_id = $1 AND exercise_name = ANY($2)`, userID, exercises)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var record PersonalRecord
+		err = rows.Scan(&record.ExerciseName, &record.RecordType, &record.Weight, &record.Value)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		previous[keyOf(record)] = record.Value
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sources, err := loadRecordSources(tx, userID, exercises)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`DELETE FROM personal_records WHERE user_id = $1 AND exercise_name = ANY($2)`, userID, exercises)
+	if err != nil {
+		return nil, err
+	}
+
+	newRecords := []PersonalRecord{}
+	for _, record := range bestRecords(userID, sources) {
+		query := `
+		INSERT INTO personal_records (user_id, exercise_name, record_type, weight, reps, value, workout_id, achieved_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+		`
+		err = tx.QueryRow(query, record.UserID, record.ExerciseName, record.RecordType, record.Weight, record.Reps, record.Value, record.WorkoutID, record.AchievedAt).Scan(&record.ID)
+		if err != nil {
+			return nil, err
+		}
+		value, held := previous[keyOf(record)]
+		if *record.WorkoutID == workoutID && (!held || record.Value > value) {
+			newRecords = append(newRecords, record)
+		}
+	}
+	return newRecords, nil
+}
+
+// loadRecordSources reads the entries the user logged on the exercises in
+// their workouts outside the trash, oldest workout first.
+func loadRecordSources(tx *sql.Tx, userID int, exercises []string) ([]recordSource, error) {
+	query := `
+	SELECT w.id, w.created_at, we.id, we.exercise_name, e.name, we.entry_type, we.sets, we.reps, we.weight, we.group_rounds
+	FROM workout_entries we
+	INNER JOIN workouts w ON w.id = we.workout_id
+	LEFT JOIN exercises e ON e.id = we.exercise_id
+	WHERE w.user_id = $1 AND w.deleted_at IS NULL AND ` + recordExerciseName + ` = ANY($2)
+	ORDER BY w.created_at, w.id, we.order_index
+	`
+	rows, err := tx.Query(query, userID, exercises)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sources := []recordSource{}
+	for rows.Next() {
+		var workoutID int
+		var createdAt time.Time
+		var entry WorkoutEntry
+		var canonicalName sql.NullString
+		var groupRounds sql.NullInt64
+		err = rows.Scan(&workoutID, &createdAt, &entry.ID, &entry.ExerciesName, &canonicalName, &entry.Type, &entry.Sets, &entry.Reps, &entry.Weight, &groupRounds)
+		if err != nil {
+			return nil, err
+		}
+		entry.canonicalName = canonicalName.String
+		if groupRounds.Valid {
+			entry.Group = &EntryGroup{Rounds: int(groupRounds.Int64)}
+		}
+
+		n := len(sources)
+		if n == 0 || sources[n-1].workoutID != workoutID {
+			sources = append(sources, recordSource{workoutID: workoutID, createdAt: createdAt})
+			n++
+		}
+		sources[n-1].entries = append(sources[n-1].entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	entries := []*WorkoutEntry{}
+	for i := range sources {
+		for j := range sources[i].entries {
+			entries = append(entries, &sources[i].entries[j])
+		}
+	}
+	return sources, loadSets(tx, entries)
+}
+
+func normalizeExerciseName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCandidateRecords(t *testing.T) {
+	entries := []WorkoutEntry{
+		{ExerciesName: "Bench  Press", Sets: 3, Reps: IntPtr(5), Weight: FloatPtr(100)},
+		{ExerciesName: "bench press", Sets: 1, Reps: IntPtr(8), Weight: FloatPtr(90)},
+		{ExerciesName: "Plank", Sets: 3, Duration: IntPtr(60)},
+	}
+
+	records := CandidateRecords(entries)
+
+	byKey := map[string]PersonalRecord{}
+	for _, record := range records {
+		assert.Equal(t, "bench press", record.ExerciseName)
+		key := record.RecordType
+		if record.RecordType == RecordRepsAtWeight {
+			key = fmt.Sprintf("%s:%.0f", key, record.Weight)
+		}
+		byKey[key] = record
+	}
+	require.Len(t, byKey, 5)
+
+	assert.Equal(t, 100.0, byKey[RecordMaxWeight].Value)
+	assert.Equal(t, 5.0, byKey[RecordRepsAtWeight+":100"].Value)
+	assert.Equal(t, 8.0, byKey[RecordRepsAtWeight+":90"].Value)
+	assert.Equal(t, 1500.0, byKey[RecordMaxEntryVolume].Value)
+	// 100 x 5 estimates 116.67, 90 x 8 only 114
+	assert.InDelta(t, 116.67, byKey[RecordEstimated1RM].Value, 0.01)
+	assert.Equal(t, 5, byKey[RecordEstimated1RM].Reps)
+}
+
+func TestEstimateOneRepMax(t *testing.T) {
+	assert.Equal(t, 0.0, EstimateOneRepMax(100, 0))
+	assert.Equal(t, 100.0, EstimateOneRepMax(100, 1))
+	assert.InDelta(t, 133.33, EstimateOneRepMax(100, 10), 0.01)
+}
+
+func TestCandidateRecordsFromSetLog(t *testing.T) {
+	entries := []WorkoutEntry{
+		{
+			ExerciesName: "Squat",
+			SetLog: []WorkoutSet{
+				{Type: SetTypeWarmup, Reps: IntPtr(5), Weight: FloatPtr(200)},
+				{Type: SetTypeWorking, Reps: IntPtr(5), Weight: FloatPtr(140)},
+				{Type: SetTypeWorking, Reps: IntPtr(3), Weight: FloatPtr(150)},
+				{Type: SetTypeDrop, Reps: IntPtr(10), Weight: FloatPtr(100)},
+			},
+		},
+	}
+
+	records := CandidateRecords(entries)
+
+	byType := map[string]PersonalRecord{}
+	for _, record := range records {
+		if record.RecordType != RecordRepsAtWeight {
+			byType[record.RecordType] = record
+		}
+	}
+
+	// the warm-up set is ignored even though it is the heaviest
+	assert.Equal(t, 150.0, byType[RecordMaxWeight].Value)
+	assert.Equal(t, 140.0*5+150*3+100*10, byType[RecordMaxEntryVolume].Value)
+	assert.InDelta(t, 165.0, byType[RecordEstimated1RM].Value, 0.01)
+}
+
+func TestBestRecords(t *testing.T) {
+	may1 := time.Date(2024, 5, 1, 18, 0, 0, 0, time.UTC)
+	may8 := may1.AddDate(0, 0, 7)
+	may15 := may1.AddDate(0, 0, 14)
+	sources := []recordSource{
+		{workoutID: 1, createdAt: may1, entries: []WorkoutEntry{{ExerciesName: "Squat", Sets: 1, Reps: IntPtr(5), Weight: FloatPtr(100)}}},
+		{workoutID: 2, createdAt: may8, entries: []WorkoutEntry{{ExerciesName: "Squat", Sets: 1, Reps: IntPtr(5), Weight: FloatPtr(110)}}},
+		{workoutID: 3, createdAt: may15, entries: []WorkoutEntry{{ExerciesName: "Squat", Sets: 1, Reps: IntPtr(5), Weight: FloatPtr(110)}}},
+	}
+
+	byKey := map[string]PersonalRecord{}
+	for _, record := range bestRecords(7, sources) {
+		byKey[fmt.Sprintf("%s:%.0f", record.RecordType, record.Weight)] = record
+	}
+
+	// a tie stays with the workout that reached it first, dated by it
+	best := byKey[RecordMaxWeight+":0"]
+	assert.Equal(t, 110.0, best.Value)
+	require.NotNil(t, best.WorkoutID)
+	assert.Equal(t, 2, *best.WorkoutID)
+	assert.Equal(t, may8, best.AchievedAt)
+	assert.Equal(t, 7, best.UserID)
+
+	// reps at a lighter weight are kept separately
+	require.Contains(t, byKey, RecordRepsAtWeight+":100")
+	assert.Equal(t, 1, *byKey[RecordRepsAtWeight+":100"].WorkoutID)
+
+	// without the second workout the record falls back to the third
+	records := bestRecords(7, []recordSource{sources[0], sources[2]})
+	for _, record := range records {
+		if record.RecordType == RecordMaxWeight {
+			assert.Equal(t, 3, *record.WorkoutID)
+		}
+	}
+}
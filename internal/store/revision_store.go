@@ -0,0 +1,215 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Revision is an immutable snapshot of a workout taken on every change.
+// Revisions are numbered from 1 per workout.
+type Revision struct {
+	ID        int       `json:"id"`
+	WorkoutID int       `json:"workout_id"`
+	Revision  int       `json:"revision"`
+	ActorID   *int      `json:"actor_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Workout   *Workout  `json:"workout,omitempty"`
+}
+
+// Change is one field that differs between two revisions. Path uses dots
+// for fields and brackets for list positions, e.g. "entries[0].reps".
+type Change struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+type PostgresRevisionStore struct {
+	db *sql.DB
+}
+
+func NewPostgresRevisionStore(db *sql.DB) *PostgresRevisionStore {
+	return &PostgresRevisionStore{db: db}
+}
+
+type RevisionStore interface {
+	GetRevisions(workoutID int64) ([]*Revision, error)
+	GetRevision(workoutID int64, revision int) (*Revision, error)
+}
+
+// saveRevision snapshots the workout as it is now. Fields that are not
+// part of the workout itself, like reactions and new records, are left
+// out.
+func saveRevision(tx *sql.Tx, workout *Workout, actorID *int) error {
+	snapshot := *workout
+	snapshot.NewRecords = nil
+	snapshot.Reactions = nil
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO workout_revisions (workout_id, revision, actor_id, snapshot)
+	SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3
+	FROM workout_revisions
+	WHERE workout_id = $1
+	`
+	_, err = tx.Exec(query, workout.ID, actorID, data)
+	return err
+}
+
+// GetRevisions lists the workout's revisions, newest first, without their
+// snapshots.
+func (pg *PostgresRevisionStore) GetRevisions(workoutID int64) ([]*Revision, error) {
+	query := `
+	SELECT id, workout_id, revision, actor_id, created_at
+	FROM workout_revisions
+	WHERE workout_id = $1
+	ORDER BY revision DESC
+	`
+	rows, err := pg.db.Query(query, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*Revision{}
+	for rows.Next() {
+		revision := &Revision{}
+		err = rows.Scan(&revision.ID, &revision.WorkoutID, &revision.Revision, &revision.ActorID, &revision.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+func (pg *PostgresRevisionStore) GetRevision(workoutID int64, number int) (*Revision, error) {
+	revision := &Revision{}
+	var snapshot []byte
+	query := `
+	SELECT id, workout_id, revision, actor_id, created_at, snapshot
+	FROM workout_revisions
+	WHERE workout_id = $1 AND revision = $2
+	`
+	err := pg.db.QueryRow(query, workoutID, number).Scan(&revision.ID, &revision.WorkoutID, &revision.Revision, &revision.ActorID, &revision.CreatedAt, &snapshot)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	revision.Workout = &Workout{}
+	err = json.Unmarshal(snapshot, revision.Workout)
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// DiffWorkouts lists the fields that changed from one workout to another.
+// Row IDs are ignored below the top level, since entries get new IDs
+// every time a workout is saved.
+func DiffWorkouts(from, to *Workout) ([]Change, error) {
+	var a, b interface{}
+	err := roundTrip(from, &a)
+	if err != nil {
+		return nil, err
+	}
+	err = roundTrip(to, &b)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	diffValues("", a, b, &changes)
+	return changes, nil
+}
+
+func roundTrip(v interface{}, out *interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func diffValues(path string, a, b interface{}, changes *[]Change) {
+	// a missing list and an empty one are the same thing
+	if isEmptyValue(a) && isEmptyValue(b) {
+		return
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := map[string]bool{}
+		for key := range av {
+			keys[key] = true
+		}
+		for key := range bv {
+			keys[key] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for key := range keys {
+			if key == "id" && path != "" {
+				continue
+			}
+			sorted = append(sorted, key)
+		}
+		sort.Strings(sorted)
+		for _, key := range sorted {
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			diffValues(child, av[key], bv[key], changes)
+		}
+		return
+
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(av) || i < len(bv); i++ {
+			var ai, bi interface{}
+			if i < len(av) {
+				ai = av[i]
+			}
+			if i < len(bv) {
+				bi = bv[i]
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), ai, bi, changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, Change{Path: path, From: a, To: b})
+	}
+}
+
+func isEmptyValue(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
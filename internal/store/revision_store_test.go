@@ -0,0 +1,43 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffWorkouts(t *testing.T) {
+	from := &Workout{
+		ID:         1,
+		Visibility: VisibilityPrivate,
+		Tags:       []string{},
+		Entries: []WorkoutEntry{
+			{ID: 10, ExerciesName: "Squat", Type: EntryTypeStrength, Sets: 3, Reps: IntPtr(5), Weight: FloatPtr(100)},
+		},
+	}
+	to := &Workout{
+		ID:         1,
+		Visibility: VisibilityPublic,
+		Entries: []WorkoutEntry{
+			{ID: 11, ExerciesName: "Squat", Type: EntryTypeStrength, Sets: 3, Reps: IntPtr(5), Weight: FloatPtr(105)},
+			{ID: 12, ExerciesName: "Plank", Type: EntryTypeTimed, Sets: 1, Duration: IntPtr(60)},
+		},
+	}
+
+	changes, err := DiffWorkouts(from, to)
+	require.NoError(t, err)
+
+	paths := map[string]Change{}
+	for _, change := range changes {
+		paths[change.Path] = change
+	}
+
+	assert.Equal(t, Change{Path: "visibility", From: VisibilityPrivate, To: VisibilityPublic}, paths["visibility"])
+	assert.Equal(t, 105.0, paths["entries[0].weight"].To)
+	assert.Nil(t, paths["entries[1]"].From)
+	assert.NotNil(t, paths["entries[1]"].To)
+	assert.NotContains(t, paths, "entries[0].id", "entry ids change on every save")
+	assert.NotContains(t, paths, "tags", "an empty list equals a missing one")
+	assert.Len(t, changes, 3)
+}
@@ -0,0 +1,114 @@
+package store
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"time"
+
+	"github.com/mhdph/go-start/internal/store/tokens"
+)
+
+// WorkoutShare is a public, read-only link to a workout. Only the hash of
+// the token is stored, so the plain token is returned once, on creation.
+type WorkoutShare struct {
+	WorkoutID int        `json:"workout_id"`
+	Token     string     `json:"token,omitempty"`
+	Expiry    *time.Time `json:"expiry"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type PostgresShareStore struct {
+	db *sql.DB
+}
+
+func NewPostgresShareStore(db *sql.DB) *PostgresShareStore {
+	return &PostgresShareStore{db: db}
+}
+
+type ShareStore interface {
+	CreateShare(workoutID, userID int, expiry time.Duration) (*WorkoutShare, error)
+	GetShareByWorkoutID(workoutID int64) (*WorkoutShare, error)
+	GetSharedWorkoutID(tokenPlainText string) (int64, error)
+	RevokeShare(workoutID int64) error
+}
+
+// CreateShare issues a new share token for the workout, replacing any
+// earlier link. A zero expiry means the link never expires.
+func (pg *PostgresShareStore) CreateShare(workoutID, userID int, expiry time.Duration) (*WorkoutShare, error) {
+	token, err := tokens.GetTokenStore(userID, tokens.ScopeShare, expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	share := &WorkoutShare{WorkoutID: workoutID, Token: token.PlainText}
+	if expiry > 0 {
+		share.Expiry = &token.Expiry
+	}
+
+	query := `
+	INSERT INTO workout_shares (hash, workout_id, user_id, expiry)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (workout_id) DO UPDATE
+	SET hash = EXCLUDED.hash, expiry = EXCLUDED.expiry, created_at = CURRENT_TIMESTAMP
+	RETURNING created_at
+	`
+	err = pg.db.QueryRow(query, token.Hash, workoutID, userID, share.Expiry).Scan(&share.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+func (pg *PostgresShareStore) GetShareByWorkoutID(workoutID int64) (*WorkoutShare, error) {
+	share := &WorkoutShare{}
+	query := `
+	SELECT workout_id, expiry, created_at
+	FROM workout_shares
+	WHERE workout_id = $1
+	`
+	err := pg.db.QueryRow(query, workoutID).Scan(&share.WorkoutID, &share.Expiry, &share.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// GetSharedWorkoutID returns the workout a live share token points to, or
+// zero when the token is unknown, revoked or expired.
+func (pg *PostgresShareStore) GetSharedWorkoutID(tokenPlainText string) (int64, error) {
+	tokenHash := sha256.Sum256([]byte(tokenPlainText))
+
+	var workoutID int64
+	query := `
+	SELECT workout_id
+	FROM workout_shares
+	WHERE hash = $1 AND (expiry IS NULL OR expiry > $2)
+	`
+	err := pg.db.QueryRow(query, tokenHash[:], time.Now()).Scan(&workoutID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return workoutID, nil
+}
+
+func (pg *PostgresShareStore) RevokeShare(workoutID int64) error {
+	result, err := pg.db.Exec(`DELETE FROM workout_shares WHERE workout_id = $1`, workoutID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
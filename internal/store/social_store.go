@@ -0,0 +1,255 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	VisibilityPrivate   = "private"
+	VisibilityFollowers = "followers"
+	VisibilityPublic    = "public"
+)
+
+var (
+	ErrInvalidVisibility = errors.New("visibility must be private, followers or public")
+	ErrUserNotFound      = errors.New("user not found")
+	ErrSelfFollow        = errors.New("users cannot follow themselves")
+)
+
+// validateVisibility defaults a workout to private, so nothing is shared
+// until its owner chooses to.
+func (w *Workout) validateVisibility() error {
+	switch w.Visibility {
+	case "":
+		w.Visibility = VisibilityPrivate
+	case VisibilityPrivate, VisibilityFollowers, VisibilityPublic:
+	default:
+		return ErrInvalidVisibility
+	}
+	return nil
+}
+
+// CanView reports whether viewerID may see the workout: owners always can,
+// followers can unless it is private, and anyone can see public workouts.
+func (w *Workout) CanView(viewerID int, following bool) bool {
+	switch {
+	case w.UserID == viewerID, w.Visibility == VisibilityPublic:
+		return true
+	case w.Visibility == VisibilityFollowers:
+		return following
+	default:
+		return false
+	}
+}
+
+// UserSummary is the public part of a user shown next to social content.
+type UserSummary struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+type Follow struct {
+	User      UserSummary `json:"user"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+type FeedItem struct {
+	Author  UserSummary `json:"author"`
+	Workout *Workout    `json:"workout"`
+}
+
+// FeedQuery describes one page of a user's feed, newest first.
+type FeedQuery struct {
+	UserID int
+	Cursor string
+	Limit  int
+}
+
+type PostgresSocialStore struct {
+	db *sql.DB
+}
+
+func NewPostgresSocialStore(db *sql.DB) *PostgresSocialStore {
+	return &PostgresSocialStore{db: db}
+}
+
+type SocialStore interface {
+	Follow(followerID, followeeID int) error
+	Unfollow(followerID, followeeID int) error
+	GetFollowers(userID int) ([]Follow, error)
+	GetFollowing(userID int) ([]Follow, error)
+	IsFollowing(followerID, followeeID int) (bool, error)
+	GetFeed(q FeedQuery) ([]FeedItem, string, error)
+}
+
+// Follow is idempotent: following someone twice is not an error.
+func (pg *PostgresSocialStore) Follow(followerID, followeeID int) error {
+	if followerID == followeeID {
+		return ErrSelfFollow
+	}
+
+	var exists bool
+	err := pg.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, followeeID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	query := `
+	INSERT INTO follows (follower_id, followee_id)
+	VALUES ($1, $2)
+	ON CONFLICT DO NOTHING
+	`
+	_, err = pg.db.Exec(query, followerID, followeeID)
+	return err
+}
+
+func (pg *PostgresSocialStore) Unfollow(followerID, followeeID int) error {
+	result, err := pg.db.Exec(`DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2`, followerID, followeeID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (pg *PostgresSocialStore) IsFollowing(followerID, followeeID int) (bool, error) {
+	var following bool
+	query := `SELECT EXISTS (SELECT 1 FROM follows WHERE follower_id = $1 AND followee_id = $2)`
+	err := pg.db.QueryRow(query, followerID, followeeID).Scan(&following)
+	return following, err
+}
+
+func (pg *PostgresSocialStore) GetFollowers(userID int) ([]Follow, error) {
+	return pg.listFollows(`
+	SELECT u.id, u.username, f.created_at
+	FROM follows f
+	INNER JOIN users u ON u.id = f.follower_id
+	WHERE f.followee_id = $1
+	ORDER BY f.created_at DESC
+	`, userID)
+}
+
+func (pg *PostgresSocialStore) GetFollowing(userID int) ([]Follow, error) {
+	return pg.listFollows(`
+	SELECT u.id, u.username, f.created_at
+	FROM follows f
+	INNER JOIN users u ON u.id = f.followee_id
+	WHERE f.follower_id = $1
+	ORDER BY f.created_at DESC
+	`, userID)
+}
+
+func (pg *PostgresSocialStore) listFollows(query string, userID int) ([]Follow, error) {
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	follows := []Follow{}
+	for rows.Next() {
+		var follow Follow
+		err = rows.Scan(&follow.User.ID, &follow.User.Username, &follow.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		follows = append(follows, follow)
+	}
+	return follows, rows.Err()
+}
+
+// GetFeed returns workouts of the users q.UserID follows, newest first.
+// Private workouts never appear. The returned cursor is empty on the last
+// page.
+func (pg *PostgresSocialStore) GetFeed(q FeedQuery) ([]FeedItem, string, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultListLimit
+	}
+	if q.Limit > MaxListLimit {
+		q.Limit = MaxListLimit
+	}
+
+	args := []interface{}{q.UserID}
+	cursorCondition := ""
+	if q.Cursor != "" {
+		createdAt, id, err := decodeCursor(q.Cursor, SortNewest)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, createdAt, id)
+		cursorCondition = "AND (w.created_at, w.id) < ($2, $3)"
+	}
+
+	// fetch one extra row to know whether there is a next page
+	args = append(args, q.Limit+1)
+	query := fmt.Sprintf(`
+	SELECT u.id, u.username, w.id, w.user_id, w.title, w.description, w.duration, w.calories_burned,
+		w.calories_estimated, w.calories_reported, w.visibility, w.version, w.created_at
+	FROM workouts w
+	INNER JOIN follows f ON f.followee_id = w.user_id AND f.follower_id = $1
+	INNER JOIN users u ON u.id = w.user_id
+	WHERE w.visibility IN ('followers', 'public') AND w.deleted_at IS NULL %s
+	ORDER BY w.created_at DESC, w.id DESC
+	LIMIT $%d
+	`, cursorCondition, len(args))
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	items := []FeedItem{}
+	workouts := []*Workout{}
+	for rows.Next() {
+		item := FeedItem{Workout: &Workout{}}
+		workout := item.Workout
+		err = rows.Scan(&item.Author.ID, &item.Author.Username, &workout.ID, &workout.UserID, &workout.Title, &workout.Description, &workout.Duration, &workout.CaloriesBurned,
+			&workout.CaloriesEstimated, &workout.CaloriesReported, &workout.Visibility, &workout.Version, &workout.CreatedAt)
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, item)
+		workouts = append(workouts, workout)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(items) > q.Limit {
+		items = items[:q.Limit]
+		workouts = workouts[:q.Limit]
+		nextCursor = encodeCursor(workouts[len(workouts)-1], SortNewest)
+	}
+
+	err = loadEntries(pg.db, workouts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = loadReactionCounts(pg.db, workouts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = loadTags(pg.db, workouts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return items, nextCursor, nil
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateVisibility(t *testing.T) {
+	workout := &Workout{}
+	require.NoError(t, workout.validateVisibility())
+	assert.Equal(t, VisibilityPrivate, workout.Visibility)
+
+	workout.Visibility = VisibilityPublic
+	require.NoError(t, workout.validateVisibility())
+	assert.Equal(t, VisibilityPublic, workout.Visibility)
+
+	workout.Visibility = "friends"
+	assert.ErrorIs(t, workout.validateVisibility(), ErrInvalidVisibility)
+}
+
+func TestWorkoutCanView(t *testing.T) {
+	tests := []struct {
+		visibility string
+		viewerID   int
+		following  bool
+		want       bool
+	}{
+		{VisibilityPrivate, 1, false, true},
+		{VisibilityPrivate, 2, true, false},
+		{VisibilityFollowers, 2, true, true},
+		{VisibilityFollowers, 2, false, false},
+		{VisibilityPublic, 2, false, true},
+	}
+
+	for _, tt := range tests {
+		workout := &Workout{UserID: 1, Visibility: tt.visibility}
+		assert.Equal(t, tt.want, workout.CanView(tt.viewerID, tt.following), "%s viewer=%d following=%v", tt.visibility, tt.viewerID, tt.following)
+	}
+}
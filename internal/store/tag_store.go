@@ -0,0 +1,311 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	TagModeAll = "all"
+	TagModeAny = "any"
+
+	maxTagLength = 50
+	// DefaultTagLimit is how many suggestions autocompletion returns.
+	DefaultTagLimit = 10
+)
+
+var (
+	ErrInvalidTag     = errors.New("tags must be between 1 and 50 characters")
+	ErrInvalidTagMode = errors.New("tag_mode must be all or any")
+)
+
+type Tag struct {
+	Name     string `json:"name"`
+	Workouts int    `json:"workouts"`
+}
+
+// Retag adds and removes tags on many of a user's workouts at once.
+type Retag struct {
+	UserID     int
+	WorkoutIDs []int64
+	Add        []string
+	Remove     []string
+}
+
+type PostgresTagStore struct {
+	db *sql.DB
+}
+
+func NewPostgresTagStore(db *sql.DB) *PostgresTagStore {
+	return &PostgresTagStore{db: db}
+}
+
+type TagStore interface {
+	GetTags(userID int, prefix string, limit int) ([]Tag, error)
+	Retag(retag Retag) (int64, error)
+}
+
+// NormalizeTag lowercases a tag and collapses its whitespace, so "Leg  Day"
+// and "leg day" are the same tag.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+	if tag == "" || utf8.RuneCountInString(tag) > maxTagLength {
+		return "", ErrInvalidTag
+	}
+	return tag, nil
+}
+
+// NormalizeTags normalizes, dedupes and sorts a list of tags.
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// GetTags suggests the user's tags starting with prefix, most used first.
+func (pg *PostgresTagStore) GetTags(userID int, prefix string, limit int) ([]Tag, error) {
+	if limit <= 0 {
+		limit = DefaultTagLimit
+	}
+	prefix = strings.ToLower(strings.Join(strings.Fields(prefix), " "))
+	// escape LIKE wildcards typed by the user
+	prefix = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix)
+
+	query := `
+	SELECT t.name, COUNT(w.id) AS workouts
+	FROM tags t
+	LEFT JOIN workout_tags wt ON wt.tag_id = t.id
+	LEFT JOIN workouts w ON w.id = wt.workout_id AND w.deleted_at IS NULL
+	WHERE t.user_id = $1 AND t.name LIKE $2 || '%'
+	GROUP BY t.id, t.name
+	ORDER BY workouts DESC, t.name
+	LIMIT $3
+	`
+	rows, err := pg.db.Query(query, userID, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		err = rows.Scan(&tag.Name, &tag.Workouts)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// Retag applies the change to those of the workouts the user owns and
+// returns how many workouts that was. Every workout whose tags change gets
+// a new version and a revision by the user, like any other edit. Tags left
+// without workouts are dropped so they stop showing up in suggestions.
+func (pg *PostgresTagStore) Retag(retag Retag) (int64, error) {
+	add, err := NormalizeTags(retag.Add)
+	if err != nil {
+		return 0, err
+	}
+	remove, err := NormalizeTags(retag.Remove)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	var owned []int64
+	rows, err := tx.Query(`SELECT id FROM workouts WHERE user_id = $1 AND id = ANY($2) AND deleted_at IS NULL ORDER BY id FOR UPDATE`, retag.UserID, retag.WorkoutIDs)
+	if err != nil {
+		return 0, err
+	}
+	for rows.Next() {
+		var id int64
+		err = rows.Scan(&id)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		owned = append(owned, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, id := range owned {
+		workout, err := getWorkoutByID(tx, id)
+		if err != nil {
+			return 0, err
+		}
+		tags, changed := retagged(workout.Tags, add, remove)
+		if !changed {
+			continue
+		}
+
+		err = saveBaseline(tx, workout.ID)
+		if err != nil {
+			return 0, err
+		}
+
+		workout.Tags = tags
+		err = saveTags(tx, workout)
+		if err != nil {
+			return 0, err
+		}
+
+		err = tx.QueryRow(`UPDATE workouts SET version = version + 1 WHERE id = $1 RETURNING version`, workout.ID).Scan(&workout.Version)
+		if err != nil {
+			return 0, err
+		}
+
+		err = saveRevision(tx, workout, &retag.UserID)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return int64(len(owned)), tx.Commit()
+}
+
+// retagged returns the normalized tags with remove taken out and add put
+// in, and whether that differs from tags.
+func retagged(tags, add, remove []string) ([]string, bool) {
+	removed := make(map[string]bool, len(remove))
+	for _, tag := range remove {
+		removed[tag] = true
+	}
+
+	next := make([]string, 0, len(tags)+len(add))
+	for _, tag := range tags {
+		if !removed[tag] {
+			next = append(next, tag)
+		}
+	}
+	next = append(next, add...)
+
+	// both lists are already normalized, so this cannot fail
+	next, _ = NormalizeTags(next)
+	current, _ := NormalizeTags(tags)
+	return next, !reflect.DeepEqual(next, current)
+}
+
+func upsertTags(tx *sql.Tx, userID int, names []string) ([]int64, error) {
+	ids := make([]int64, 0, len(names))
+	for _, name := range names {
+		var id int64
+		query := `
+		INSERT INTO tags (user_id, name)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id
+		`
+		err := tx.QueryRow(query, userID, name).Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func deleteUnusedTags(tx *sql.Tx, userID int) error {
+	query := `
+	DELETE FROM tags t
+	WHERE t.user_id = $1 AND NOT EXISTS (SELECT 1 FROM workout_tags wt WHERE wt.tag_id = t.id)
+	`
+	_, err := tx.Exec(query, userID)
+	return err
+}
+
+// saveTags replaces the workout's tags with workout.Tags.
+func saveTags(tx *sql.Tx, workout *Workout) error {
+	tags, err := NormalizeTags(workout.Tags)
+	if err != nil {
+		return err
+	}
+	workout.Tags = tags
+
+	_, err = tx.Exec(`DELETE FROM workout_tags WHERE workout_id = $1`, workout.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(tags) > 0 {
+		tagIDs, err := upsertTags(tx, workout.UserID, tags)
+		if err != nil {
+			return err
+		}
+		query := `
+		INSERT INTO workout_tags (workout_id, tag_id)
+		SELECT $1, UNNEST($2::BIGINT[])
+		`
+		_, err = tx.Exec(query, workout.ID, tagIDs)
+		if err != nil {
+			return err
+		}
+	}
+
+	return deleteUnusedTags(tx, workout.UserID)
+}
+
+// loadTags fills in the tags of every workout with a single query.
+func loadTags(q queryer, workouts []*Workout) error {
+	if len(workouts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(workouts))
+	byID := make(map[int]*Workout, len(workouts))
+	for _, workout := range workouts {
+		workout.Tags = []string{}
+		ids = append(ids, int64(workout.ID))
+		byID[workout.ID] = workout
+	}
+
+	query := `
+	SELECT wt.workout_id, t.name
+	FROM workout_tags wt
+	INNER JOIN tags t ON t.id = wt.tag_id
+	WHERE wt.workout_id = ANY($1)
+	ORDER BY t.name
+	`
+	rows, err := q.Query(query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workoutID int
+		var name string
+		err = rows.Scan(&workoutID, &name)
+		if err != nil {
+			return err
+		}
+		byID[workoutID].Tags = append(byID[workoutID].Tags, name)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := NormalizeTags([]string{"Leg  Day", "deload", " leg day ", "Travel"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deload", "leg day", "travel"}, tags)
+
+	_, err = NormalizeTags([]string{"   "})
+	assert.ErrorIs(t, err, ErrInvalidTag)
+
+	_, err = NormalizeTags([]string{strings.Repeat("x", 51)})
+	assert.ErrorIs(t, err, ErrInvalidTag)
+}
+
+func TestRetagged(t *testing.T) {
+	tags, changed := retagged([]string{"deload", "leg day"}, []string{"travel"}, []string{"deload"})
+	assert.True(t, changed)
+	assert.Equal(t, []string{"leg day", "travel"}, tags)
+
+	// adding a tag the workout has and removing one it lacks is no change
+	tags, changed = retagged([]string{"leg day"}, []string{"leg day"}, []string{"travel"})
+	assert.False(t, changed)
+	assert.Equal(t, []string{"leg day"}, tags)
+
+	_, changed = retagged([]string{}, nil, []string{"travel"})
+	assert.False(t, changed)
+}
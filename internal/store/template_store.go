@@ -0,0 +1,332 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var ErrDuplicateTemplate = errors.New("a template with this name already exists")
+
+type WorkoutTemplate struct {
+	ID             int            `json:"id"`
+	UserID         int            `json:"user_id"`
+	Name           string         `json:"name"`
+	Title          string         `json:"title"`
+	Description    string         `json:"description"`
+	Duration       int            `json:"duration"`
+	CaloriesBurned int            `json:"calories_burned"`
+	Entries        []WorkoutEntry `json:"entries"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// NewTemplateFromWorkout copies the layout of a workout into a template.
+func NewTemplateFromWorkout(name string, workout *Workout) *WorkoutTemplate {
+	return &WorkoutTemplate{
+		UserID:         workout.UserID,
+		Name:           name,
+		Title:          workout.Title,
+		Description:    workout.Description,
+		Duration:       workout.Duration,
+		CaloriesBurned: workout.CaloriesBurned,
+		Entries:        copyEntries(workout.Entries),
+	}
+}
+
+// ToWorkout builds a new, unsaved workout from the template.
+func (t *WorkoutTemplate) ToWorkout(userID int) *Workout {
+	return &Workout{
+		UserID:         userID,
+		Title:          t.Title,
+		Description:    t.Description,
+		Duration:       t.Duration,
+		CaloriesBurned: t.CaloriesBurned,
+		Entries:        copyEntries(t.Entries),
+	}
+}
+
+// copyEntries copies entries together with their groups and set logs,
+// clearing the IDs so the copies can be saved as new rows.
+func copyEntries(entries []WorkoutEntry) []WorkoutEntry {
+	copied := make([]WorkoutEntry, len(entries))
+	copy(copied, entries)
+	for i := range copied {
+		entry := &copied[i]
+		entry.ID = 0
+		if entry.Group != nil {
+			group := *entry.Group
+			entry.Group = &group
+		}
+		if entry.SetLog != nil {
+			entry.SetLog = append([]WorkoutSet(nil), entry.SetLog...)
+			for j := range entry.SetLog {
+				entry.SetLog[j].ID = 0
+			}
+		}
+	}
+	return copied
+}
+
+type PostgresTemplateStore struct {
+	db *sql.DB
+}
+
+func NewPostgresTemplateStore(db *sql.DB) *PostgresTemplateStore {
+	return &PostgresTemplateStore{db: db}
+}
+
+type TemplateStore interface {
+	CreateTemplate(*WorkoutTemplate) (*WorkoutTemplate, error)
+	GetTemplateByID(id int64) (*WorkoutTemplate, error)
+	GetTemplatesByUserID(userID int64) ([]*WorkoutTemplate, error)
+	DeleteTemplate(id int64) error
+}
+
+func (pg *PostgresTemplateStore) CreateTemplate(template *WorkoutTemplate) (*WorkoutTemplate, error) {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	query := `
+	INSERT INTO workout_templates (user_id, name, title, description, duration, calories_burned)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at
+	`
+
+	err = tx.QueryRow(query, template.UserID, template.Name, template.Title, template.Description, template.Duration, template.CaloriesBurned).Scan(&template.ID, &template.CreatedAt)
+	if isUniqueViolation(err) {
+		return nil, ErrDuplicateTemplate
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = resolveExercises(tx, template.UserID, template.Entries)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range template.Entries {
+		entry := &template.Entries[i]
+		err = entry.normalizeWeight()
+		if err != nil {
+			return nil, err
+		}
+		err = entry.applySetLog()
+		if err != nil {
+			return nil, err
+		}
+		err = entry.validateGroup()
+		if err != nil {
+			return nil, err
+		}
+
+		if entry.Type == "" {
+			entry.Type = entry.inferEntryType()
+		}
+
+		groupLabel, groupType, groupRounds, groupRest := entry.groupColumns()
+		query = `INSERT INTO workout_template_entries (template_id, exercise_id, exercise_name, entry_type, sets, reps, duration, weight,
+			distance, avg_heart_rate, rounds, work_seconds, rest_seconds, notes, order_index,
+			group_label, group_type, group_rounds, group_rest_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		RETURNING id
+		`
+		err = tx.QueryRow(query, template.ID, entry.ExerciseID, entry.ExerciesName, entry.Type, entry.Sets, entry.Reps, entry.Duration, entry.Weight,
+			entry.Distance, entry.AvgHeartRate, entry.Rounds, entry.WorkSeconds, entry.RestSeconds, entry.Notes, entry.OrderIndex,
+			groupLabel, groupType, groupRounds, groupRest).Scan(&entry.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = insertTemplateSets(tx, entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = validateGroups(template.Entries)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func (pg *PostgresTemplateStore) GetTemplateByID(id int64) (*WorkoutTemplate, error) {
+	template := &WorkoutTemplate{}
+	query := `
+	SELECT id, user_id, name, title, description, duration, calories_burned, created_at
+	FROM workout_templates
+	WHERE id = $1
+	`
+	err := pg.db.QueryRow(query, id).Scan(&template.ID, &template.UserID, &template.Name, &template.Title, &template.Description, &template.Duration, &template.CaloriesBurned, &template.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	entryQuery := `
+	SELECT id, exercise_id, exercise_name, entry_type, sets, reps, duration, weight, distance, avg_heart_rate,
+		rounds, work_seconds, rest_seconds, notes, order_index,
+		group_label, group_type, group_rounds, group_rest_seconds
+	FROM workout_template_entries
+	WHERE template_id = $1
+	ORDER BY order_index
+	`
+	rows, err := pg.db.Query(entryQuery, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry WorkoutEntry
+		var groupLabel, groupType sql.NullString
+		var groupRounds sql.NullInt64
+		var groupRest *int
+		err = rows.Scan(
+			&entry.ID,
+			&entry.ExerciseID,
+			&entry.ExerciesName,
+			&entry.Type,
+			&entry.Sets,
+			&entry.Reps,
+			&entry.Duration,
+			&entry.Weight,
+			&entry.Distance,
+			&entry.AvgHeartRate,
+			&entry.Rounds,
+			&entry.WorkSeconds,
+			&entry.RestSeconds,
+			&entry.Notes,
+			&entry.OrderIndex,
+			&groupLabel,
+			&groupType,
+			&groupRounds,
+			&groupRest,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if groupLabel.Valid {
+			entry.Group = &EntryGroup{Label: groupLabel.String, Type: groupType.String, Rounds: int(groupRounds.Int64), RestSeconds: groupRest}
+		}
+		entry.WeightUnit = UnitKilograms
+		template.Entries = append(template.Entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = loadTemplateSets(pg.db, template.Entries)
+	if err != nil {
+		return nil, err
+	}
+	return template, nil
+}
+
+func insertTemplateSets(tx *sql.Tx, entry *WorkoutEntry) error {
+	for i := range entry.SetLog {
+		set := &entry.SetLog[i]
+		query := `
+		INSERT INTO workout_template_sets (template_entry_id, set_number, set_type, reps, weight, rpe, rir, rest_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+		`
+		err := tx.QueryRow(query, entry.ID, set.SetNumber, set.Type, set.Reps, set.Weight, set.RPE, set.RIR, set.RestSeconds).Scan(&set.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTemplateSets fills in the set log of every template entry with a
+// single query.
+func loadTemplateSets(q queryer, entries []WorkoutEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(entries))
+	byID := make(map[int]*WorkoutEntry, len(entries))
+	for i := range entries {
+		ids = append(ids, int64(entries[i].ID))
+		byID[entries[i].ID] = &entries[i]
+	}
+
+	query := `
+	SELECT template_entry_id, id, set_number, set_type, reps, weight, rpe, rir, rest_seconds
+	FROM workout_template_sets
+	WHERE template_entry_id = ANY($1)
+	ORDER BY template_entry_id, set_number
+	`
+	rows, err := q.Query(query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID int
+		var set WorkoutSet
+		err = rows.Scan(&entryID, &set.ID, &set.SetNumber, &set.Type, &set.Reps, &set.Weight, &set.RPE, &set.RIR, &set.RestSeconds)
+		if err != nil {
+			return err
+		}
+		entry := byID[entryID]
+		entry.SetLog = append(entry.SetLog, set)
+	}
+	return rows.Err()
+}
+
+func (pg *PostgresTemplateStore) GetTemplatesByUserID(userID int64) ([]*WorkoutTemplate, error) {
+	query := `
+	SELECT id, user_id, name, title, description, duration, calories_burned, created_at
+	FROM workout_templates
+	WHERE user_id = $1
+	ORDER BY name
+	`
+	rows, err := pg.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []*WorkoutTemplate{}
+
+	for rows.Next() {
+		template := &WorkoutTemplate{}
+		err = rows.Scan(&template.ID, &template.UserID, &template.Name, &template.Title, &template.Description, &template.Duration, &template.CaloriesBurned, &template.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, rows.Err()
+}
+
+func (pg *PostgresTemplateStore) DeleteTemplate(id int64) error {
+	query := `DELETE FROM workout_templates WHERE id = $1`
+	result, err := pg.db.Exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
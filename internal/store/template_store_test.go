@@ -0,0 +1,74 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateRoundTrip(t *testing.T) {
+	exerciseID := 4
+	workout := &Workout{
+		ID:          9,
+		UserID:      3,
+		Title:       "Circuit day",
+		Description: "three rounds",
+		Duration:    45,
+		Entries: []WorkoutEntry{
+			{
+				ID:           21,
+				ExerciseID:   &exerciseID,
+				ExerciesName: "Back Squat",
+				Type:         EntryTypeStrength,
+				Sets:         1,
+				Reps:         IntPtr(8),
+				Weight:       FloatPtr(60),
+				AvgHeartRate: IntPtr(140),
+				OrderIndex:   0,
+				SetLog:       []WorkoutSet{{ID: 31, SetNumber: 1, Type: SetTypeWorking, Reps: IntPtr(8), Weight: FloatPtr(60)}},
+				Group:        &EntryGroup{Label: "A", Type: GroupTypeCircuit, Rounds: 3, RestSeconds: IntPtr(90)},
+			},
+			{
+				ID:           22,
+				ExerciesName: "Push-up",
+				Sets:         1,
+				Reps:         IntPtr(15),
+				OrderIndex:   1,
+				Group:        &EntryGroup{Label: "A", Type: GroupTypeCircuit, Rounds: 3, RestSeconds: IntPtr(90)},
+			},
+		},
+	}
+
+	template := NewTemplateFromWorkout("Circuit", workout)
+	assert.Equal(t, "Circuit", template.Name)
+	assert.Equal(t, 3, template.UserID)
+	require.Len(t, template.Entries, 2)
+
+	started := template.ToWorkout(5)
+	assert.Equal(t, 5, started.UserID)
+	assert.Equal(t, 0, started.ID)
+	assert.Equal(t, "Circuit day", started.Title)
+	require.Len(t, started.Entries, 2)
+
+	for i, entry := range started.Entries {
+		original := workout.Entries[i]
+		assert.Equal(t, 0, entry.ID)
+		assert.Equal(t, original.ExerciseID, entry.ExerciseID)
+		assert.Equal(t, original.AvgHeartRate, entry.AvgHeartRate)
+		assert.Equal(t, *original.Group, *entry.Group)
+		require.Len(t, entry.SetLog, len(original.SetLog))
+		for j, set := range entry.SetLog {
+			assert.Equal(t, 0, set.ID)
+			assert.Equal(t, original.SetLog[j].Reps, set.Reps)
+		}
+	}
+
+	// the copies share nothing with the workout they came from
+	started.Entries[0].Group.Rounds = 5
+	started.Entries[0].SetLog[0].SetNumber = 2
+	assert.Equal(t, 3, workout.Entries[0].Group.Rounds)
+	assert.Equal(t, 31, workout.Entries[0].SetLog[0].ID)
+	assert.Equal(t, 1, workout.Entries[0].SetLog[0].SetNumber)
+	assert.Equal(t, 3, template.Entries[0].Group.Rounds)
+}
@@ -9,6 +9,7 @@ import (
 
 const (
 	ScopeAuthentication = "authentication"
+	ScopeShare          = "share"
 )
 
 type Token struct {
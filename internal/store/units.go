@@ -0,0 +1,187 @@
+package store
+
+import (
+	"errors"
+	"math"
+)
+
+const (
+	UnitKilograms = "kg"
+	UnitPounds    = "lb"
+
+	kilogramsPerPound = 0.45359237
+)
+
+var ErrInvalidWeightUnit = errors.New("weight unit must be kg or lb")
+
+func ValidWeightUnit(unit string) bool {
+	return unit == UnitKilograms || unit == UnitPounds
+}
+
+func ToKilograms(weight float64, unit string) float64 {
+	if unit == UnitPounds {
+		return weight * kilogramsPerPound
+	}
+	return weight
+}
+
+// FromKilograms converts a canonical weight for display, rounded to two
+// decimals.
+func FromKilograms(weight float64, unit string) float64 {
+	if unit == UnitPounds {
+		weight = weight / kilogramsPerPound
+	}
+	return math.Round(weight*100) / 100
+}
+
+// SetDefaultWeightUnit marks entries that did not say which unit their
+// weights are in as using unit.
+func SetDefaultWeightUnit(entries []WorkoutEntry, unit string) {
+	for i := range entries {
+		if entries[i].WeightUnit == "" {
+			entries[i].WeightUnit = unit
+		}
+	}
+}
+
+// normalizeWeight converts the entry and its sets to kilograms, remembering
+// the unit they were entered in. It is a no-op for entries already in kg.
+func (e *WorkoutEntry) normalizeWeight() error {
+	if e.WeightUnit == "" {
+		e.WeightUnit = UnitKilograms
+	}
+	if !ValidWeightUnit(e.WeightUnit) {
+		return ErrInvalidWeightUnit
+	}
+	if e.WeightUnit != UnitKilograms || !ValidWeightUnit(e.EnteredUnit) {
+		e.EnteredUnit = e.WeightUnit
+	}
+	if e.WeightUnit == UnitKilograms {
+		return nil
+	}
+
+	if e.Weight != nil {
+		weight := ToKilograms(*e.Weight, e.WeightUnit)
+		e.Weight = &weight
+	}
+	for i := range e.SetLog {
+		if e.SetLog[i].Weight != nil {
+			weight := ToKilograms(*e.SetLog[i].Weight, e.WeightUnit)
+			e.SetLog[i].Weight = &weight
+		}
+	}
+	e.WeightUnit = UnitKilograms
+	return nil
+}
+
+// convertWeight expresses a canonical (kg) entry in unit. The set log is
+// copied first, so copies of the entry taken before keep their kg values.
+func (e *WorkoutEntry) convertWeight(unit string) {
+	if e.Weight != nil {
+		weight := FromKilograms(*e.Weight, unit)
+		e.Weight = &weight
+	}
+	e.SetLog = append([]WorkoutSet(nil), e.SetLog...)
+	for i := range e.SetLog {
+		if e.SetLog[i].Weight != nil {
+			weight := FromKilograms(*e.SetLog[i].Weight, unit)
+			e.SetLog[i].Weight = &weight
+		}
+	}
+	e.WeightUnit = unit
+}
+
+// ConvertWeights expresses every weight of a stored workout in unit.
+func (w *Workout) ConvertWeights(unit string) {
+	for i := range w.Entries {
+		w.Entries[i].convertWeight(unit)
+	}
+	for i := range w.NewRecords {
+		w.NewRecords[i].ConvertWeights(unit)
+	}
+}
+
+// KeepStoredWeights undoes the drift of showing stored weights rounded in
+// unit and reading them back. An entry whose weights all still show as the
+// stored entry's gets the stored kilograms and entered unit back; in any
+// other entry, unchanged weights get their exact conversion instead of the
+// rounded one. Entries and sets are matched by ID, and stored is in kg.
+func (w *Workout) KeepStoredWeights(stored []WorkoutEntry, unit string) {
+	byID := make(map[int]*WorkoutEntry, len(stored))
+	for i := range stored {
+		byID[stored[i].ID] = &stored[i]
+	}
+
+	for i := range w.Entries {
+		entry := &w.Entries[i]
+		original, ok := byID[entry.ID]
+		if !ok || entry.ID == 0 || entry.WeightUnit != unit {
+			continue
+		}
+		entry.keepStoredWeights(original, unit)
+	}
+}
+
+func (e *WorkoutEntry) keepStoredWeights(stored *WorkoutEntry, unit string) {
+	sets := make(map[int]*WorkoutSet, len(stored.SetLog))
+	for i := range stored.SetLog {
+		sets[stored.SetLog[i].ID] = &stored.SetLog[i]
+	}
+
+	// keep replaces an unchanged weight with its exact value in unit and
+	// reports whether it was unchanged
+	keep := func(shown **float64, stored *float64) bool {
+		if *shown == nil || stored == nil {
+			return *shown == nil && stored == nil
+		}
+		if **shown != FromKilograms(*stored, unit) {
+			return false
+		}
+		exact := *stored
+		if unit == UnitPounds {
+			exact = *stored / kilogramsPerPound
+		}
+		*shown = &exact
+		return true
+	}
+
+	unchanged := keep(&e.Weight, stored.Weight) && len(e.SetLog) == len(stored.SetLog)
+	for i := range e.SetLog {
+		set := &e.SetLog[i]
+		original, ok := sets[set.ID]
+		if !ok || set.ID == 0 {
+			unchanged = false
+			continue
+		}
+		if !keep(&set.Weight, original.Weight) {
+			unchanged = false
+		}
+	}
+	if !unchanged {
+		return
+	}
+
+	e.Weight = stored.Weight
+	for i := range e.SetLog {
+		e.SetLog[i].Weight = sets[e.SetLog[i].ID].Weight
+	}
+	e.WeightUnit = UnitKilograms
+	e.EnteredUnit = stored.EnteredUnit
+}
+
+// ConvertWeights expresses every weight of a stored template in unit.
+func (t *WorkoutTemplate) ConvertWeights(unit string) {
+	for i := range t.Entries {
+		t.Entries[i].convertWeight(unit)
+	}
+}
+
+// ConvertWeights expresses the record's weight, and its value unless it is
+// a rep count, in unit.
+func (r *PersonalRecord) ConvertWeights(unit string) {
+	r.Weight = FromKilograms(r.Weight, unit)
+	if r.RecordType != RecordRepsAtWeight {
+		r.Value = FromKilograms(r.Value, unit)
+	}
+	r.WeightUnit = unit
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeightUnitRoundTrip(t *testing.T) {
+	entry := WorkoutEntry{
+		WeightUnit: UnitPounds,
+		Weight:     FloatPtr(225),
+		SetLog:     []WorkoutSet{{Weight: FloatPtr(135)}},
+	}
+
+	require.NoError(t, entry.normalizeWeight())
+	assert.Equal(t, UnitKilograms, entry.WeightUnit)
+	assert.Equal(t, UnitPounds, entry.EnteredUnit)
+	assert.InDelta(t, 102.058, *entry.Weight, 0.001)
+
+	// normalizing a canonical entry again must not convert it twice
+	require.NoError(t, entry.normalizeWeight())
+	assert.InDelta(t, 102.058, *entry.Weight, 0.001)
+	assert.Equal(t, UnitPounds, entry.EnteredUnit)
+
+	entry.convertWeight(UnitPounds)
+	assert.Equal(t, 225.0, *entry.Weight)
+	assert.Equal(t, 135.0, *entry.SetLog[0].Weight)
+	assert.Equal(t, UnitPounds, entry.WeightUnit)
+
+	invalid := WorkoutEntry{WeightUnit: "stone"}
+	assert.ErrorIs(t, invalid.normalizeWeight(), ErrInvalidWeightUnit)
+}
+
+func TestKeepStoredWeights(t *testing.T) {
+	stored := []WorkoutEntry{
+		{ID: 1, Weight: FloatPtr(100), WeightUnit: UnitKilograms, EnteredUnit: UnitKilograms,
+			SetLog: []WorkoutSet{{ID: 11, Weight: FloatPtr(60)}, {ID: 12, Weight: FloatPtr(100)}}},
+		{ID: 2, Weight: FloatPtr(80), WeightUnit: UnitKilograms, EnteredUnit: UnitKilograms},
+	}
+	workout := &Workout{Entries: append([]WorkoutEntry(nil), stored...)}
+	workout.ConvertWeights(UnitPounds)
+	assert.Equal(t, 100.0, *stored[0].SetLog[1].Weight, "converting must not touch the stored copy")
+
+	// the client sends the first entry back as it got it and changes the
+	// second one's weight
+	*workout.Entries[1].Weight = 185
+	workout.KeepStoredWeights(stored, UnitPounds)
+
+	untouched := workout.Entries[0]
+	require.NoError(t, untouched.normalizeWeight())
+	assert.Equal(t, 100.0, *untouched.Weight)
+	assert.Equal(t, 60.0, *untouched.SetLog[0].Weight)
+	assert.Equal(t, UnitKilograms, untouched.EnteredUnit)
+
+	edited := workout.Entries[1]
+	require.NoError(t, edited.normalizeWeight())
+	assert.InDelta(t, 83.915, *edited.Weight, 0.001)
+	assert.Equal(t, UnitPounds, edited.EnteredUnit)
+
+	// an unchanged weight next to a changed one converts back exactly
+	workout = &Workout{Entries: append([]WorkoutEntry(nil), stored[0])}
+	workout.ConvertWeights(UnitPounds)
+	*workout.Entries[0].SetLog[0].Weight = 140
+	workout.KeepStoredWeights(stored, UnitPounds)
+	entry := workout.Entries[0]
+	require.NoError(t, entry.normalizeWeight())
+	assert.InDelta(t, 100.0, *entry.Weight, 1e-9)
+	assert.InDelta(t, 100.0, *entry.SetLog[1].Weight, 1e-9)
+	assert.InDelta(t, 63.503, *entry.SetLog[0].Weight, 0.001)
+}
@@ -40,13 +40,15 @@ func (p password) Matches(plainText string) (bool, error) {
 }
 
 type User struct {
-	ID        int       `json:"Id"`
-	Username  string    `json:"string"`
-	Email     string    `json:"email"`
-	Password  password  `json:"_"`
-	Bio       string    `json:"bio"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID         int       `json:"Id"`
+	Username   string    `json:"string"`
+	Email      string    `json:"email"`
+	Password   password  `json:"_"`
+	Bio        string    `json:"bio"`
+	WeightUnit string    `json:"weight_unit"`
+	BodyWeight *float64  `json:"body_weight"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
 }
 
 var AnonymousUser = &User{
@@ -61,6 +63,14 @@ func (u *User) IsAnnoymous() bool {
 	return u == AnonymousUser
 }
 
+// PreferredWeightUnit is the unit weights are shown to the user in.
+func (u *User) PreferredWeightUnit() string {
+	if ValidWeightUnit(u.WeightUnit) {
+		return u.WeightUnit
+	}
+	return UnitKilograms
+}
+
 type PostgresUserStore struct {
 	db *sql.DB
 }
@@ -76,11 +86,16 @@ type UserStore interface {
 	GetUserByUsername(username string) (*User, error)
 	UpdateUser(*User) error
 	GetUserToken(scope, tokenPlainText string) (*User, error)
+	UpdatePreferences(*User) error
 }
 
 func (s *PostgresUserStore) CreateUser(user *User) error {
-	query := `INSERT INTO users (username, email, password, bio) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`
-	row := s.db.QueryRow(query, user.Username, user.Email, user.Password, user.Bio)
+	if user.WeightUnit == "" {
+		user.WeightUnit = UnitKilograms
+	}
+
+	query := `INSERT INTO users (username, email, password_hash, bio, weight_unit) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`
+	row := s.db.QueryRow(query, user.Username, user.Email, user.Password.hash, user.Bio, user.WeightUnit)
 
 	err := row.Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
@@ -116,6 +131,28 @@ func (s *PostgresUserStore) UpdateUser(user *User) error {
 	return nil
 }
 
+// UpdatePreferences saves the user's weight unit and body weight (in kg).
+func (s *PostgresUserStore) UpdatePreferences(user *User) error {
+	if !ValidWeightUnit(user.WeightUnit) {
+		return ErrInvalidWeightUnit
+	}
+
+	query := `UPDATE users SET weight_unit = $1, body_weight = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`
+	result, err := s.db.Exec(query, user.WeightUnit, user.BodyWeight, user.ID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 func (s *PostgresUserStore) GetUserByUsername(username string) (*User, error) {
 	user := &User{
 		Password: password{},
@@ -184,9 +221,9 @@ func (s *PostgresUserStore) GetUserToken(scope, tokenPlainText string) (*User, e
 	tokenHash := sha256.Sum256([]byte(tokenPlainText))
 
 	query := `
-	SELECT u.id, u.email, u.username, u.password, u.bio, u.created_at, u.updated_at 
+	SELECT u.id, u.username, u.email, u.password_hash, u.bio, u.weight_unit, u.body_weight, u.created_at, u.updated_at
 	FROM users u 
-	INNER JOIN tokens t ON t.users.id = u.user.id 
+	INNER JOIN tokens t ON t.user_id = u.id
 	WHERE t.hash = $1 AND t.scope = $2 AND t.expiry > $3`
 
 	user := &User{
@@ -199,6 +236,8 @@ func (s *PostgresUserStore) GetUserToken(scope, tokenPlainText string) (*User, e
 		&user.Email,
 		&user.Password.hash,
 		&user.Bio,
+		&user.WeightUnit,
+		&user.BodyWeight,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
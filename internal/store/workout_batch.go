@@ -0,0 +1,166 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+)
+
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+
+	MaxBatchSize = 100
+)
+
+var (
+	ErrInvalidBatchOp = errors.New("op must be create, update or delete")
+	// ErrBatchAborted marks the operations of an atomic batch that were
+	// rolled back, or never tried, because another one failed, and the
+	// operations that refer to a create that failed.
+	ErrBatchAborted = errors.New("not applied because another operation in the batch failed")
+)
+
+// BatchOp is one change in a batch. Creates carry the new workout. Updates
+// and deletes carry the ID and version of the workout they change, or Ref,
+// the index of an earlier create in the same batch whose workout they
+// change. Updates also carry Patch, which turns the workout as it stands
+// when the op runs into the one to save. After a create or update has been
+// applied, Workout holds the saved workout.
+type BatchOp struct {
+	Op      string
+	Workout *Workout
+	ID      int64
+	Ref     *int
+	Version int
+	Patch   func(current *Workout) (*Workout, error)
+}
+
+// ApplyBatch runs ops in order and returns one error per op, nil for the
+// ones that were applied. An atomic batch runs in a single transaction and
+// is committed only if every op succeeds; otherwise each op commits on its
+// own. Either way every op is checked against the workouts as the earlier
+// ops left them, so one batch can change a workout more than once. Updates
+// and deletes of workouts that do not belong to actorID fail with
+// sql.ErrNoRows. The returned error is set only when the batch could not
+// be run at all.
+func (pg *PostgresWorkoutStore) ApplyBatch(ops []BatchOp, actorID int, atomic bool) ([]error, error) {
+	results := make([]error, len(ops))
+	if !atomic {
+		for i := range ops {
+			results[i] = resolveBatchOp(ops, i, results)
+			if results[i] == nil {
+				results[i] = pg.applyBatchOp(&ops[i], actorID)
+			}
+		}
+		return results, nil
+	}
+
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	failed := false
+	for i := range ops {
+		if failed {
+			results[i] = ErrBatchAborted
+			continue
+		}
+		results[i] = resolveBatchOp(ops, i, results)
+		if results[i] == nil {
+			results[i] = applyBatchOp(tx, &ops[i], actorID)
+		}
+		failed = results[i] != nil
+	}
+
+	if !failed {
+		err = tx.Commit()
+		if err != nil {
+			return nil, err
+		}
+		return results, nil
+	}
+
+	for i := range results {
+		if results[i] == nil {
+			results[i] = ErrBatchAborted
+		}
+	}
+	return results, nil
+}
+
+// resolveBatchOp checks op i and points it at the workout created by the
+// op it refers to, given the results of the ops before it.
+func resolveBatchOp(ops []BatchOp, i int, results []error) error {
+	op := &ops[i]
+	if op.Op != BatchCreate && op.Op != BatchUpdate && op.Op != BatchDelete {
+		return ErrInvalidBatchOp
+	}
+	if op.Ref == nil {
+		return nil
+	}
+	ref := *op.Ref
+	if op.Op == BatchCreate || ref < 0 || ref >= i || ops[ref].Op != BatchCreate {
+		return ErrInvalidBatchOp
+	}
+	if results[ref] != nil {
+		return ErrBatchAborted
+	}
+	op.ID = int64(ops[ref].Workout.ID)
+	return nil
+}
+
+// applyBatchOp runs a single op of a best-effort batch in its own
+// transaction.
+func (pg *PostgresWorkoutStore) applyBatchOp(op *BatchOp, actorID int) error {
+	tx, err := pg.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = applyBatchOp(tx, op, actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func applyBatchOp(tx *sql.Tx, op *BatchOp, actorID int) error {
+	if op.Op == BatchCreate {
+		return insertWorkout(tx, op.Workout)
+	}
+
+	current, err := getWorkoutByID(tx, op.ID)
+	if err != nil {
+		return err
+	}
+	if current.UserID != actorID {
+		return sql.ErrNoRows
+	}
+	if current.Version != op.Version {
+		return ErrVersionConflict
+	}
+
+	switch op.Op {
+	case BatchUpdate:
+		workout, err := op.Patch(current)
+		if err != nil {
+			return err
+		}
+		err = updateWorkout(tx, workout, actorID)
+		if err != nil {
+			return err
+		}
+		op.Workout = workout
+		return nil
+	case BatchDelete:
+		return deleteWorkout(tx, op.ID, op.Version, actorID)
+	}
+	return ErrInvalidBatchOp
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyBatchRejectsUnknownOps(t *testing.T) {
+	pg := NewPostgresWorkoutStore(nil)
+
+	errs, err := pg.ApplyBatch([]BatchOp{{Op: "upsert"}, {Op: ""}}, 1, false)
+	require.NoError(t, err)
+	require.Len(t, errs, 2)
+	for _, err := range errs {
+		assert.ErrorIs(t, err, ErrInvalidBatchOp)
+	}
+}
+
+func TestResolveBatchOp(t *testing.T) {
+	first, second := 0, 1
+	ops := []BatchOp{
+		{Op: BatchCreate, Workout: &Workout{ID: 42}},
+		{Op: BatchCreate, Workout: &Workout{}},
+		{Op: BatchUpdate, Ref: &first, Version: 1},
+		{Op: BatchDelete, Ref: &second, Version: 1},
+		{Op: BatchUpdate, Ref: &first},
+	}
+	results := []error{nil, ErrInvalidWorkout}
+
+	require.NoError(t, resolveBatchOp(ops, 2, results))
+	assert.Equal(t, int64(42), ops[2].ID)
+
+	// the create it refers to failed
+	assert.ErrorIs(t, resolveBatchOp(ops, 3, results), ErrBatchAborted)
+
+	// refs must name an earlier create
+	self := 4
+	ops[4].Ref = &self
+	assert.ErrorIs(t, resolveBatchOp(ops, 4, results), ErrInvalidBatchOp)
+	update := 2
+	ops[4].Ref = &update
+	assert.ErrorIs(t, resolveBatchOp(ops, 4, results), ErrInvalidBatchOp)
+}
@@ -0,0 +1,166 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+const (
+	SetTypeWarmup  = "warmup"
+	SetTypeWorking = "working"
+	SetTypeDrop    = "drop"
+	SetTypeFailure = "failure"
+)
+
+var ErrInvalidWorkoutSet = errors.New("invalid workout set")
+
+// WorkoutSet is one logged set of an entry.
+type WorkoutSet struct {
+	ID          int      `json:"id"`
+	SetNumber   int      `json:"set_number"`
+	Type        string   `json:"type"`
+	Reps        *int     `json:"reps"`
+	Weight      *float64 `json:"weight"`
+	RPE         *float64 `json:"rpe"`
+	RIR         *int     `json:"rir"`
+	RestSeconds *int     `json:"rest_seconds"`
+}
+
+func (s *WorkoutSet) validate() error {
+	switch s.Type {
+	case "":
+		s.Type = SetTypeWorking
+	case SetTypeWarmup, SetTypeWorking, SetTypeDrop, SetTypeFailure:
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidWorkoutSet, s.Type)
+	}
+	if s.Reps != nil && *s.Reps < 0 {
+		return fmt.Errorf("%w: reps cannot be negative", ErrInvalidWorkoutSet)
+	}
+	if s.Weight != nil && *s.Weight < 0 {
+		return fmt.Errorf("%w: weight cannot be negative", ErrInvalidWorkoutSet)
+	}
+	if s.RPE != nil && (*s.RPE < 1 || *s.RPE > 10) {
+		return fmt.Errorf("%w: rpe must be between 1 and 10", ErrInvalidWorkoutSet)
+	}
+	if s.RIR != nil && *s.RIR < 0 {
+		return fmt.Errorf("%w: rir cannot be negative", ErrInvalidWorkoutSet)
+	}
+	if s.RestSeconds != nil && *s.RestSeconds < 0 {
+		return fmt.Errorf("%w: rest_seconds cannot be negative", ErrInvalidWorkoutSet)
+	}
+	return nil
+}
+
+// applySetLog validates the logged sets and derives the aggregate Sets,
+// Reps and Weight columns from them, so readers that only know about the
+// aggregate entry keep working. The top set is the heaviest working set.
+func (e *WorkoutEntry) applySetLog() error {
+	if len(e.SetLog) == 0 {
+		return nil
+	}
+
+	var top *WorkoutSet
+	working := 0
+	for i := range e.SetLog {
+		set := &e.SetLog[i]
+		set.SetNumber = i + 1
+		err := set.validate()
+		if err != nil {
+			return fmt.Errorf("set %d: %w", set.SetNumber, err)
+		}
+		if set.Type == SetTypeWarmup {
+			continue
+		}
+		working++
+		if top == nil || setWeight(set) > setWeight(top) || (setWeight(set) == setWeight(top) && setReps(set) > setReps(top)) {
+			top = set
+		}
+	}
+
+	if top == nil {
+		e.Sets = len(e.SetLog)
+		return nil
+	}
+
+	e.Sets = working
+	if top.Reps != nil {
+		reps := *top.Reps
+		e.Reps = &reps
+	}
+	if top.Weight != nil {
+		weight := *top.Weight
+		e.Weight = &weight
+	}
+	return nil
+}
+
+func insertSets(tx *sql.Tx, entry *WorkoutEntry) error {
+	for i := range entry.SetLog {
+		set := &entry.SetLog[i]
+		query := `
+		INSERT INTO workout_sets (entry_id, set_number, set_type, reps, weight, rpe, rir, rest_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+		`
+		err := tx.QueryRow(query, entry.ID, set.SetNumber, set.Type, set.Reps, set.Weight, set.RPE, set.RIR, set.RestSeconds).Scan(&set.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSets fills in the set log of every entry with a single query. Entries
+// logged before per-set tracking simply keep an empty log.
+func loadSets(q queryer, entries []*WorkoutEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(entries))
+	byID := make(map[int]*WorkoutEntry, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, int64(entry.ID))
+		byID[entry.ID] = entry
+	}
+
+	query := `
+	SELECT entry_id, id, set_number, set_type, reps, weight, rpe, rir, rest_seconds
+	FROM workout_sets
+	WHERE entry_id = ANY($1)
+	ORDER BY entry_id, set_number
+	`
+	rows, err := q.Query(query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entryID int
+		var set WorkoutSet
+		err = rows.Scan(&entryID, &set.ID, &set.SetNumber, &set.Type, &set.Reps, &set.Weight, &set.RPE, &set.RIR, &set.RestSeconds)
+		if err != nil {
+			return err
+		}
+		entry := byID[entryID]
+		entry.SetLog = append(entry.SetLog, set)
+	}
+	return rows.Err()
+}
+
+func setWeight(s *WorkoutSet) float64 {
+	if s.Weight == nil {
+		return 0
+	}
+	return *s.Weight
+}
+
+func setReps(s *WorkoutSet) int {
+	if s.Reps == nil {
+		return 0
+	}
+	return *s.Reps
+}
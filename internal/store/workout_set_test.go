@@ -0,0 +1,37 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplySetLog(t *testing.T) {
+	entry := WorkoutEntry{
+		ExerciesName: "Bench press",
+		SetLog: []WorkoutSet{
+			{Type: SetTypeWarmup, Reps: IntPtr(10), Weight: FloatPtr(60)},
+			{Reps: IntPtr(8), Weight: FloatPtr(90)},
+			{Reps: IntPtr(6), Weight: FloatPtr(97.5)},
+			{Type: SetTypeFailure, Reps: IntPtr(4), Weight: FloatPtr(97.5)},
+		},
+	}
+
+	require.NoError(t, entry.applySetLog())
+
+	assert.Equal(t, 3, entry.Sets)
+	require.NotNil(t, entry.Reps)
+	assert.Equal(t, 6, *entry.Reps)
+	require.NotNil(t, entry.Weight)
+	assert.Equal(t, 97.5, *entry.Weight)
+	assert.Equal(t, SetTypeWorking, entry.SetLog[1].Type)
+	assert.Equal(t, 4, entry.SetLog[3].SetNumber)
+
+	invalid := WorkoutEntry{SetLog: []WorkoutSet{{Type: "superset"}}}
+	assert.ErrorIs(t, invalid.applySetLog(), ErrInvalidWorkoutSet)
+
+	rpe := 11.0
+	invalid = WorkoutEntry{SetLog: []WorkoutSet{{RPE: &rpe}}}
+	assert.ErrorIs(t, invalid.applySetLog(), ErrInvalidWorkoutSet)
+}
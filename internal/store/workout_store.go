@@ -2,27 +2,58 @@ package store
 
 import (
 	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Workout struct {
-	ID             int            `json:"id"`
-	UserID         int            `json:"user_id"`
-	Title          string         `joson:"title"`
-	Description    string         `joson:"description"`
-	Duration       int            `joson:"duration"`
-	CaloriesBurned int            `joson:"calories_burned"`
-	Entries        []WorkoutEntry `json:"entries"`
+	ID                int              `json:"id"`
+	UserID            int              `json:"user_id"`
+	Title             string           `json:"title"`
+	Description       string           `json:"description"`
+	Duration          int              `json:"duration"`
+	CaloriesBurned    int              `json:"calories_burned"`
+	CaloriesEstimated *int             `json:"calories_estimated"`
+	CaloriesReported  *int             `json:"calories_reported"`
+	Visibility        string           `json:"visibility"`
+	Version           int              `json:"version"`
+	Tags              []string         `json:"tags"`
+	Entries           []WorkoutEntry   `json:"entries"`
+	CreatedAt         time.Time        `json:"created_at"`
+	DeletedAt         *time.Time       `json:"deleted_at,omitempty"`
+	Cardio            *CardioSummary   `json:"cardio,omitempty"`
+	Reactions         map[string]int   `json:"reactions,omitempty"`
+	NewRecords        []PersonalRecord `json:"new_records,omitempty"`
 }
 
 type WorkoutEntry struct {
-	ID           int    `json:"id"`
-	ExerciesName string `json:"exercise_name"`
-	Sets         int    `json:"sets"`
-	Reps         *int   `json:"reps"`
-	Duration     *int   `json:"duration"`
-	Weight       *int   `json:"weight"`
-	Notes        string `json:"notes"`
-	OrderIndex   int    `json:"order_index"`
+	ID           int          `json:"id"`
+	ExerciseID   *int         `json:"exercise_id"`
+	ExerciesName string       `json:"exercise_name"`
+	Type         string       `json:"type"`
+	Sets         int          `json:"sets"`
+	Reps         *int         `json:"reps,omitempty"`
+	Duration     *int         `json:"duration,omitempty"`
+	Weight       *float64     `json:"weight,omitempty"`
+	WeightUnit   string       `json:"weight_unit"`
+	EnteredUnit  string       `json:"entered_unit"`
+	Distance     *float64     `json:"distance,omitempty"`
+	Pace         *float64     `json:"pace,omitempty"`
+	AvgHeartRate *int         `json:"avg_heart_rate,omitempty"`
+	Rounds       *int         `json:"rounds,omitempty"`
+	WorkSeconds  *int         `json:"work_seconds,omitempty"`
+	RestSeconds  *int         `json:"rest_seconds,omitempty"`
+	Notes        string       `json:"notes"`
+	OrderIndex   int          `json:"order_index"`
+	SetLog       []WorkoutSet `json:"set_log,omitempty"`
+	Group        *EntryGroup  `json:"group,omitempty"`
+
+	// canonicalName is the catalog name once ExerciseID is resolved.
+	canonicalName string
 }
 
 type PostgresWorkoutStore struct {
@@ -36,9 +67,85 @@ func NewPostgresWorkoutStore(db *sql.DB) *PostgresWorkoutStore {
 type WorkoutStore interface {
 	CreateWorkOut(*Workout) (*Workout, error)
 	GetWorkoutByID(id int64) (*Workout, error)
-	UpdateWorkout(*Workout) error
-	DeleteWorkout(id int64) error
+	UpdateWorkout(workout *Workout, actorID int) error
+	DeleteWorkout(id int64, version int, actorID int) error
+	ListTrash(userID int64) ([]*Workout, error)
+	RestoreWorkout(id, userID int64) error
+	PurgeTrash(before time.Time) (int64, error)
 	GetWorkoutsByUserID(userID int64) ([]*Workout, error)
+	ListWorkouts(filter WorkoutFilter) ([]*Workout, string, error)
+	ImportWorkouts(userID int, workouts []*Workout) ([]*Workout, error)
+	ApplyBatch(ops []BatchOp, actorID int, atomic bool) ([]error, error)
+}
+
+const (
+	SortNewest       = "-created_at"
+	SortOldest       = "created_at"
+	SortLongest      = "-duration"
+	SortShortest     = "duration"
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+
+	// TrashRetention is how long a deleted workout can be restored.
+	TrashRetention = 30 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidCursor   = errors.New("invalid cursor")
+	ErrInvalidSort     = errors.New("invalid sort")
+	ErrVersionConflict = errors.New("workout was changed by someone else")
+	ErrInvalidWorkout  = errors.New("invalid workout")
+)
+
+// WorkoutFilter describes one page of a user's workout history.
+type WorkoutFilter struct {
+	UserID         int64
+	From           *time.Time
+	To             *time.Time
+	Title          string
+	Tags           []string
+	TagMode        string
+	Sort           string
+	Cursor         string
+	Limit          int
+	IncludeEntries bool
+}
+
+// Validate checks the workout and its entries before it is saved. Entries
+// are checked on copies, the store still normalizes them as it writes.
+func (w *Workout) Validate() error {
+	if strings.TrimSpace(w.Title) == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidWorkout)
+	}
+	if w.Duration < 0 {
+		return fmt.Errorf("%w: duration cannot be negative", ErrInvalidWorkout)
+	}
+	if w.CaloriesReported != nil && *w.CaloriesReported < 0 {
+		return fmt.Errorf("%w: calories cannot be negative", ErrInvalidWorkout)
+	}
+	_, err := NormalizeTags(w.Tags)
+	if err != nil {
+		return err
+	}
+	err = w.validateVisibility()
+	if err != nil {
+		return err
+	}
+
+	entries := make([]WorkoutEntry, len(w.Entries))
+	for i, entry := range w.Entries {
+		entry.SetLog = append([]WorkoutSet(nil), entry.SetLog...)
+		if entry.Group != nil {
+			group := *entry.Group
+			entry.Group = &group
+		}
+		err = entry.prepare()
+		if err != nil {
+			return err
+		}
+		entries[i] = entry
+	}
+	return validateGroups(entries)
 }
 
 func (pg *PostgresWorkoutStore) CreateWorkOut(workout *Workout) (*Workout, error) {
@@ -49,77 +156,153 @@ func (pg *PostgresWorkoutStore) CreateWorkOut(workout *Workout) (*Workout, error
 
 	defer tx.Rollback()
 
-	query := ` 
-	INSERT INTO workouts (user_id, title, description, duration, calories_burned) 
-	VALUES ($1, $2, $3, $4, $5) 
-	RETURNING id
-	`
+	err = insertWorkout(tx, workout)
+	if err != nil {
+		return nil, err
+	}
 
-	err = tx.QueryRow(query, workout.UserID, workout.Title, workout.Description, workout.Duration, workout.CaloriesBurned).Scan(&workout.ID)
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+	return workout, nil
+}
 
+// ImportWorkouts saves workouts in a single transaction, skipping any that
+// already exist for the user with the same title on the same day. It
+// returns the workouts that were actually inserted.
+func (pg *PostgresWorkoutStore) ImportWorkouts(userID int, workouts []*Workout) ([]*Workout, error) {
+	tx, err := pg.db.Begin()
 	if err != nil {
 		return nil, err
 	}
 
-	for _, entry := range workout.Entries {
-		query = `INSERT INTO workout_entries (workout_id,exercise_name,sets,reps,duration,weight,notes,order_index)
-		VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+	defer tx.Rollback()
+
+	imported := []*Workout{}
+	for _, workout := range workouts {
+		workout.UserID = userID
+
+		var exists bool
+		query := `
+		SELECT EXISTS (
+			SELECT 1 FROM workouts
+			WHERE user_id = $1 AND LOWER(title) = LOWER($2) AND created_at::date = $3::date AND deleted_at IS NULL
+		)
 		`
-		err = tx.QueryRow(query, workout.ID, entry.ExerciesName, entry.Sets, entry.Reps, entry.Duration, entry.Weight, entry.Notes, entry.OrderIndex).Scan(&entry.ID)
+		err = tx.QueryRow(query, userID, workout.Title, workout.CreatedAt).Scan(&exists)
 		if err != nil {
 			return nil, err
 		}
+		if exists {
+			continue
+		}
+
+		err = insertWorkout(tx, workout)
+		if err != nil {
+			return nil, fmt.Errorf("import %q on %s: %w", workout.Title, workout.CreatedAt.Format(dateLayout), err)
+		}
+		imported = append(imported, workout)
 	}
+
 	err = tx.Commit()
 	if err != nil {
 		return nil, err
 	}
-	return workout, nil
+	return imported, nil
+}
+
+// insertWorkout writes a new workout with its entries and records any
+// personal records it sets. A zero CreatedAt means now.
+func insertWorkout(tx *sql.Tx, workout *Workout) error {
+	err := workout.validateVisibility()
+	if err != nil {
+		return err
+	}
+
+	var createdAt *time.Time
+	if !workout.CreatedAt.IsZero() {
+		createdAt = &workout.CreatedAt
+	}
+
+	query := ` 
+	INSERT INTO workouts (user_id, title, description, duration, calories_burned, calories_estimated, calories_reported, visibility, created_at) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE($9, CURRENT_TIMESTAMP)) 
+	RETURNING id, version, created_at
+	`
+
+	err = tx.QueryRow(query, workout.UserID, workout.Title, workout.Description, workout.Duration, workout.CaloriesBurned, workout.CaloriesEstimated, workout.CaloriesReported, workout.Visibility, createdAt).Scan(&workout.ID, &workout.Version, &workout.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	err = insertEntries(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	err = insertCardio(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	err = saveTags(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	workout.NewRecords, err = saveRecords(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	return saveRevision(tx, workout, &workout.UserID)
 }
 
 func (pg *PostgresWorkoutStore) GetWorkoutByID(id int64) (*Workout, error) {
+	return getWorkoutByID(pg.db, id)
+}
+
+// dbQueryer is satisfied by both *sql.DB and *sql.Tx.
+type dbQueryer interface {
+	queryer
+	rowQueryer
+}
+
+func getWorkoutByID(q dbQueryer, id int64) (*Workout, error) {
 	workout := &Workout{}
-	query := `SELECT id,title,description,duration,calories_burned FROM workouts WHERE id = $1`
-	err := pg.db.QueryRow(query, id).Scan(&workout.ID, &workout.Title, &workout.Description, &workout.Duration, &workout.CaloriesBurned)
+	query := `SELECT id,user_id,title,description,duration,calories_burned,calories_estimated,calories_reported,visibility,version,created_at FROM workouts WHERE id = $1 AND deleted_at IS NULL`
+	err := q.QueryRow(query, id).Scan(&workout.ID, &workout.UserID, &workout.Title, &workout.Description, &workout.Duration, &workout.CaloriesBurned, &workout.CaloriesEstimated, &workout.CaloriesReported, &workout.Visibility, &workout.Version, &workout.CreatedAt)
 	if err != nil {
 		return nil, err
 	}
 
-	entryQuery := `SELECT id,exercise_name, sets, reps, duration, wiegh, note, order_index
-	FROM workout_entries 
-	WHERE workout_id = $1 
-	ORDER BY order_inedx
-	`
-	rows, err := pg.db.Query(entryQuery, id)
+	err = loadEntries(q, []*Workout{workout})
+	if err != nil {
+		return nil, err
+	}
 
+	err = loadCardio(q, workout)
 	if err != nil {
 		return nil, err
 	}
 
-	defer rows.Close()
+	err = loadReactionCounts(q, []*Workout{workout})
+	if err != nil {
+		return nil, err
+	}
 
-	for rows.Next() {
-		var entry WorkoutEntry
-		err = rows.Scan(
-			&entry.ID,
-			&entry.ExerciesName,
-			&entry.Sets,
-			&entry.Reps,
-			&entry.Duration,
-			&entry.Weight,
-			&entry.Notes,
-			&entry.OrderIndex,
-		)
-		if err != nil {
-			return nil, err
-		}
-		workout.Entries = append(workout.Entries, entry)
+	err = loadTags(q, []*Workout{workout})
+	if err != nil {
+		return nil, err
 	}
 
 	return workout, nil
 }
 
-func (pg *PostgresWorkoutStore) UpdateWorkout(workout *Workout) error {
+// UpdateWorkout saves the workout if it is still at workout.Version, bumps
+// the version and records a revision attributed to actorID.
+func (pg *PostgresWorkoutStore) UpdateWorkout(workout *Workout, actorID int) error {
 	tx, err := pg.db.Begin()
 	if err != nil {
 		return err
@@ -127,55 +310,259 @@ func (pg *PostgresWorkoutStore) UpdateWorkout(workout *Workout) error {
 
 	defer tx.Rollback()
 
+	err = updateWorkout(tx, workout, actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// saveBaseline gives a workout created before revisions existed a first
+// revision of its stored state, so the change about to be made can be
+// diffed. The workout row stays locked until tx ends, so concurrent
+// changes cannot both take a baseline.
+func saveBaseline(tx *sql.Tx, id int) error {
+	var locked int
+	err := tx.QueryRow(`SELECT id FROM workouts WHERE id = $1 FOR UPDATE`, id).Scan(&locked)
+	if err == sql.ErrNoRows {
+		// the versioned write that follows reports the missing workout
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var hasRevisions bool
+	err = tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM workout_revisions WHERE workout_id = $1)`, id).Scan(&hasRevisions)
+	if err != nil || hasRevisions {
+		return err
+	}
+
+	stored, err := getWorkoutByID(tx, int64(id))
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return saveRevision(tx, stored, nil)
+}
+
+func updateWorkout(tx *sql.Tx, workout *Workout, actorID int) error {
+	err := workout.validateVisibility()
+	if err != nil {
+		return err
+	}
+
+	err = saveBaseline(tx, workout.ID)
+	if err != nil {
+		return err
+	}
+
 	query := ` 
 	UPDATE workouts 
-	SET title = $1, description = $2, duraion = $3, calories_burned = $4
-	WHERE id = $5
+	SET title = $1, description = $2, duration = $3, calories_burned = $4, calories_estimated = $5, calories_reported = $6, visibility = $7, version = version + 1
+	WHERE id = $8 AND deleted_at IS NULL AND version = $9
+	RETURNING version
 	`
 
-	result, err := tx.Exec(query, workout.Title, workout.Description, workout.Duration, workout.CaloriesBurned, workout.ID)
+	err = tx.QueryRow(query, workout.Title, workout.Description, workout.Duration, workout.CaloriesBurned, workout.CaloriesEstimated, workout.CaloriesReported, workout.Visibility, workout.ID, workout.Version).Scan(&workout.Version)
+	if err == sql.ErrNoRows {
+		return versionError(tx, workout.ID)
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`DELETE FROM workout_entries WHERE workout_id = $1`, workout.ID)
 
 	if err != nil {
 		return err
 	}
 
-	RowsAffected, err := result.RowsAffected()
+	err = insertEntries(tx, workout)
 	if err != nil {
-		return nil
+		return err
 	}
-	if RowsAffected == 0 {
-		return sql.ErrNoRows
+
+	err = saveTags(tx, workout)
+	if err != nil {
+		return err
+	}
+
+	workout.NewRecords, err = refreshRecords(tx, workout.UserID, workout.ID)
+	if err != nil {
+		return err
 	}
 
-	_, err = tx.Exec(`DELETE FROM workout_entrues WHERE workout_Id = $1`, workout.ID)
+	return saveRevision(tx, workout, &actorID)
+}
 
+// DeleteWorkout moves the workout to the trash if it is still at version,
+// recording a revision attributed to actorID. It stays restorable for
+// TrashRetention, after which PurgeTrash removes it for good.
+func (pg *PostgresWorkoutStore) DeleteWorkout(id int64, version int, actorID int) error {
+	tx, err := pg.db.Begin()
 	if err != nil {
 		return err
 	}
 
-	for _, entry := range workout.Entries {
-		query := `INSERT INTO workout_entries (workout_id, exercise_name, sets, reps, duration, weight, notes, order_index)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-		`
-		_, err = tx.Exec(query, workout.ID, entry.ExerciesName, entry.Sets, entry.Reps, entry.Duration, entry.Weight, entry.Notes, entry.OrderIndex)
+	defer tx.Rollback()
+
+	err = deleteWorkout(tx, id, version, actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// deleteWorkout trashes the workout, withdraws the records it held and
+// records the trashing as a revision.
+func deleteWorkout(tx *sql.Tx, id int64, version int, actorID int) error {
+	err := saveBaseline(tx, int(id))
+	if err != nil {
+		return err
+	}
+
+	workout, err := getWorkoutByID(tx, id)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	UPDATE workouts SET deleted_at = CURRENT_TIMESTAMP, version = version + 1
+	WHERE id = $1 AND deleted_at IS NULL AND version = $2
+	RETURNING version, deleted_at
+	`
+	err = tx.QueryRow(query, id, version).Scan(&workout.Version, &workout.DeletedAt)
+	if err == sql.ErrNoRows {
+		return versionError(tx, int(id))
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = refreshRecords(tx, workout.UserID, workout.ID)
+	if err != nil {
+		return err
+	}
+
+	return saveRevision(tx, workout, &actorID)
+}
+
+type rowQueryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// versionError explains why a versioned write matched no row: either the
+// workout is gone or it has moved on to a newer version.
+func versionError(q rowQueryer, id int) error {
+	var exists bool
+	err := q.QueryRow(`SELECT EXISTS (SELECT 1 FROM workouts WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return ErrVersionConflict
+}
+
+// ListTrash returns the user's restorable workouts, most recently deleted
+// first.
+func (pg *PostgresWorkoutStore) ListTrash(userID int64) ([]*Workout, error) {
+	query := `
+	SELECT id, user_id, title, description, duration, calories_burned, calories_estimated, calories_reported, visibility, version, created_at, deleted_at
+	FROM workouts
+	WHERE user_id = $1 AND deleted_at IS NOT NULL AND deleted_at > $2
+	ORDER BY deleted_at DESC, id DESC
+	`
+	rows, err := pg.db.Query(query, userID, time.Now().Add(-TrashRetention))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	workouts := []*Workout{}
+	for rows.Next() {
+		workout := &Workout{}
+		err = rows.Scan(&workout.ID, &workout.UserID, &workout.Title, &workout.Description, &workout.Duration, &workout.CaloriesBurned, &workout.CaloriesEstimated, &workout.CaloriesReported, &workout.Visibility, &workout.Version, &workout.CreatedAt, &workout.DeletedAt)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		workouts = append(workouts, workout)
 	}
-	return tx.Commit()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	err = loadEntries(pg.db, workouts)
+	if err != nil {
+		return nil, err
+	}
+	return workouts, nil
 }
 
-func (pg *PostgresWorkoutStore) DeleteWorkout(id int64) error {
-	query := `DELETE FROM workouts WHERE id = $1`
-	_, err := pg.db.Exec(query, id)
+// RestoreWorkout takes the workout back out of the trash, along with the
+// records it still beats, and records the restore as a revision by its
+// owner.
+func (pg *PostgresWorkoutStore) RestoreWorkout(id, userID int64) error {
+	tx, err := pg.db.Begin()
 	if err != nil {
 		return err
 	}
-	return nil
+
+	defer tx.Rollback()
+
+	query := `
+	UPDATE workouts SET deleted_at = NULL, version = version + 1
+	WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL AND deleted_at > $3
+	`
+	result, err := tx.Exec(query, id, userID, time.Now().Add(-TrashRetention))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, err = refreshRecords(tx, int(userID), int(id))
+	if err != nil {
+		return err
+	}
+
+	workout, err := getWorkoutByID(tx, id)
+	if err != nil {
+		return err
+	}
+	actorID := int(userID)
+	err = saveRevision(tx, workout, &actorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PurgeTrash permanently deletes workouts trashed before the given time,
+// with their entries, and returns how many were removed.
+func (pg *PostgresWorkoutStore) PurgeTrash(before time.Time) (int64, error) {
+	result, err := pg.db.Exec(`DELETE FROM workouts WHERE deleted_at IS NOT NULL AND deleted_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 func (pg *PostgresWorkoutStore) GetWorkoutsByUserID(userID int64) ([]*Workout, error) {
-	query := `SELECT id, title, description, duration, calories_burned FROM workouts WHERE user_id = $1`
+	query := `SELECT id, title, description, duration, calories_burned FROM workouts WHERE user_id = $1 AND deleted_at IS NULL`
 	rows, err := pg.db.Query(query, userID)
 	if err != nil {
 		return nil, err
@@ -194,3 +581,323 @@ func (pg *PostgresWorkoutStore) GetWorkoutsByUserID(userID int64) ([]*Workout, e
 	}
 	return workouts, nil
 }
+
+// ListWorkouts returns one page of workouts for filter.UserID together with
+// the cursor for the next page, which is empty on the last page.
+func (pg *PostgresWorkoutStore) ListWorkouts(filter WorkoutFilter) ([]*Workout, string, error) {
+	query, args, err := listWorkoutsQuery(&filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	rows, err := pg.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	workouts := []*Workout{}
+	for rows.Next() {
+		workout := &Workout{}
+		err = rows.Scan(&workout.ID, &workout.UserID, &workout.Title, &workout.Description, &workout.Duration, &workout.CaloriesBurned, &workout.CaloriesEstimated, &workout.CaloriesReported, &workout.Visibility, &workout.Version, &workout.CreatedAt)
+		if err != nil {
+			return nil, "", err
+		}
+		workouts = append(workouts, workout)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(workouts) > filter.Limit {
+		workouts = workouts[:filter.Limit]
+		nextCursor = encodeCursor(workouts[len(workouts)-1], filter.Sort)
+	}
+
+	if filter.IncludeEntries {
+		err = loadEntries(pg.db, workouts)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	err = loadReactionCounts(pg.db, workouts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = loadTags(pg.db, workouts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return workouts, nextCursor, nil
+}
+
+// workoutSorts maps each sort to the column and direction it orders by.
+var workoutSorts = map[string]struct{ column, direction string }{
+	SortNewest:   {"created_at", "DESC"},
+	SortOldest:   {"created_at", "ASC"},
+	SortLongest:  {"duration", "DESC"},
+	SortShortest: {"duration", "ASC"},
+}
+
+// listWorkoutsQuery builds the query for one page of the filter, filling in
+// the default sort and limit.
+func listWorkoutsQuery(filter *WorkoutFilter) (string, []interface{}, error) {
+	if filter.Limit <= 0 || filter.Limit > MaxListLimit {
+		filter.Limit = DefaultListLimit
+	}
+	if filter.Sort == "" {
+		filter.Sort = SortNewest
+	}
+	order, ok := workoutSorts[filter.Sort]
+	if !ok {
+		return "", nil, ErrInvalidSort
+	}
+	column, direction := order.column, order.direction
+
+	conditions := []string{"user_id = $1", "deleted_at IS NULL"}
+	args := []interface{}{filter.UserID}
+
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	if filter.Title != "" {
+		args = append(args, "%"+filter.Title+"%")
+		conditions = append(conditions, fmt.Sprintf("title ILIKE $%d", len(args)))
+	}
+	if len(filter.Tags) > 0 {
+		tags, err := NormalizeTags(filter.Tags)
+		if err != nil {
+			return "", nil, err
+		}
+		args = append(args, tags)
+		tagQuery := fmt.Sprintf(`SELECT wt.workout_id FROM workout_tags wt INNER JOIN tags t ON t.id = wt.tag_id WHERE t.user_id = $1 AND t.name = ANY($%d)`, len(args))
+		switch filter.TagMode {
+		case "", TagModeAll:
+			// every tag must match, so count the distinct matches
+			args = append(args, len(tags))
+			tagQuery += fmt.Sprintf(` GROUP BY wt.workout_id HAVING COUNT(*) = $%d`, len(args))
+		case TagModeAny:
+		default:
+			return "", nil, ErrInvalidTagMode
+		}
+		conditions = append(conditions, "id IN ("+tagQuery+")")
+	}
+	if filter.Cursor != "" {
+		value, id, err := decodeCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return "", nil, err
+		}
+		operator := "<"
+		if direction == "ASC" {
+			operator = ">"
+		}
+		args = append(args, value, id)
+		conditions = append(conditions, fmt.Sprintf("(%s, id) %s ($%d, $%d)", column, operator, len(args)-1, len(args)))
+	}
+
+	// fetch one extra row to know whether there is a next page
+	args = append(args, filter.Limit+1)
+	query := fmt.Sprintf(`
+	SELECT id, user_id, title, description, duration, calories_burned, calories_estimated, calories_reported, visibility, version, created_at
+	FROM workouts
+	WHERE %s
+	ORDER BY %s %s, id %s
+	LIMIT $%d
+	`, strings.Join(conditions, " AND "), column, direction, direction, len(args))
+	return query, args, nil
+}
+
+// loadEntries fills in the entries of every workout with a single query.
+func loadEntries(q queryer, workouts []*Workout) error {
+	if len(workouts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(workouts))
+	byID := make(map[int]*Workout, len(workouts))
+	for _, workout := range workouts {
+		ids = append(ids, int64(workout.ID))
+		byID[workout.ID] = workout
+	}
+
+	query := `
+	SELECT workout_id, id, exercise_id, exercise_name, entry_type, sets, reps, duration, weight, entered_unit,
+		distance, avg_heart_rate, rounds, work_seconds, rest_seconds, notes, order_index,
+		group_label, group_type, group_rounds, group_rest_seconds
+	FROM workout_entries
+	WHERE workout_id = ANY($1)
+	ORDER BY workout_id, order_index
+	`
+	rows, err := q.Query(query, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var workoutID int
+		var entry WorkoutEntry
+		var groupLabel, groupType sql.NullString
+		var groupRounds sql.NullInt64
+		var groupRest *int
+		err = rows.Scan(
+			&workoutID,
+			&entry.ID,
+			&entry.ExerciseID,
+			&entry.ExerciesName,
+			&entry.Type,
+			&entry.Sets,
+			&entry.Reps,
+			&entry.Duration,
+			&entry.Weight,
+			&entry.EnteredUnit,
+			&entry.Distance,
+			&entry.AvgHeartRate,
+			&entry.Rounds,
+			&entry.WorkSeconds,
+			&entry.RestSeconds,
+			&entry.Notes,
+			&entry.OrderIndex,
+			&groupLabel,
+			&groupType,
+			&groupRounds,
+			&groupRest,
+		)
+		if err != nil {
+			return err
+		}
+		if groupLabel.Valid {
+			entry.Group = &EntryGroup{Label: groupLabel.String, Type: groupType.String, Rounds: int(groupRounds.Int64), RestSeconds: groupRest}
+		}
+		entry.WeightUnit = UnitKilograms
+		entry.derivePace()
+		workout := byID[workoutID]
+		workout.Entries = append(workout.Entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	entries := []*WorkoutEntry{}
+	for _, workout := range workouts {
+		for i := range workout.Entries {
+			entries = append(entries, &workout.Entries[i])
+		}
+	}
+	return loadSets(q, entries)
+}
+
+// insertEntries writes the workout's entries and their logged sets,
+// filling in the generated IDs.
+func insertEntries(tx *sql.Tx, workout *Workout) error {
+	err := resolveExercises(tx, workout.UserID, workout.Entries)
+	if err != nil {
+		return err
+	}
+
+	for i := range workout.Entries {
+		err = insertEntry(tx, workout.ID, &workout.Entries[i])
+		if err != nil {
+			return err
+		}
+	}
+	return validateGroups(workout.Entries)
+}
+
+// prepare converts the entry to kilograms, folds in its set log and
+// validates it. Exercises must already be resolved.
+func (e *WorkoutEntry) prepare() error {
+	err := e.normalizeWeight()
+	if err != nil {
+		return err
+	}
+	err = e.applySetLog()
+	if err != nil {
+		return err
+	}
+	err = e.validate()
+	if err != nil {
+		return err
+	}
+	return e.validateGroup()
+}
+
+func insertEntry(tx *sql.Tx, workoutID int, entry *WorkoutEntry) error {
+	err := entry.prepare()
+	if err != nil {
+		return err
+	}
+
+	groupLabel, groupType, groupRounds, groupRest := entry.groupColumns()
+	query := `INSERT INTO workout_entries (workout_id, exercise_id, exercise_name, entry_type, sets, reps, duration, weight, entered_unit,
+		distance, avg_heart_rate, rounds, work_seconds, rest_seconds, notes, order_index,
+		group_label, group_type, group_rounds, group_rest_seconds)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+	RETURNING id
+	`
+	err = tx.QueryRow(query, workoutID, entry.ExerciseID, entry.ExerciesName, entry.Type, entry.Sets, entry.Reps, entry.Duration, entry.Weight, entry.EnteredUnit,
+		entry.Distance, entry.AvgHeartRate, entry.Rounds, entry.WorkSeconds, entry.RestSeconds, entry.Notes, entry.OrderIndex,
+		groupLabel, groupType, groupRounds, groupRest).Scan(&entry.ID)
+	if err != nil {
+		return err
+	}
+
+	return insertSets(tx, entry)
+}
+
+// encodeCursor points after workout in a list ordered by sort. The sort is
+// part of the cursor so that it cannot be reused with another one.
+func encodeCursor(workout *Workout, sort string) string {
+	value := workout.CreatedAt.Format(time.RFC3339Nano)
+	if workoutSorts[sort].column == "duration" {
+		value = strconv.Itoa(workout.Duration)
+	}
+	raw := sort + "|" + value + "|" + strconv.Itoa(workout.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor reads a cursor issued by encodeCursor for the same sort,
+// returning the value of the sort column and the workout ID to continue
+// after.
+func decodeCursor(cursor, sort string) (interface{}, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 3)
+	if len(parts) != 3 {
+		return nil, 0, ErrInvalidCursor
+	}
+	if parts[0] != sort {
+		return nil, 0, fmt.Errorf("%w: it was issued for sort %s", ErrInvalidCursor, parts[0])
+	}
+
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+
+	if workoutSorts[sort].column == "duration" {
+		duration, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, 0, ErrInvalidCursor
+		}
+		return duration, id, nil
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	return createdAt, id, nil
+}
@@ -2,7 +2,9 @@ package store
 
 import (
 	"database/sql"
+	"encoding/base64"
 	"testing"
+	"time"
 
 	_ "github.com/jackc/pgx/v4"
 	"github.com/stretchr/testify/assert"
@@ -92,3 +94,141 @@ func TestCreateWorkout(t *testing.T) {
 func IntPtr(i int) *int {
 	return &i
 }
+
+func FloatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestWorkoutCursor(t *testing.T) {
+	createdAt := time.Date(2025, 3, 14, 18, 30, 0, 123, time.UTC)
+	workout := &Workout{ID: 42, Duration: 55, CreatedAt: createdAt}
+
+	tests := []struct {
+		name      string
+		issuedFor string
+		usedFor   string
+		wantValue interface{}
+		wantErr   bool
+	}{
+		{name: "newest", issuedFor: SortNewest, usedFor: SortNewest, wantValue: createdAt},
+		{name: "oldest", issuedFor: SortOldest, usedFor: SortOldest, wantValue: createdAt},
+		{name: "longest", issuedFor: SortLongest, usedFor: SortLongest, wantValue: 55},
+		{name: "shortest", issuedFor: SortShortest, usedFor: SortShortest, wantValue: 55},
+		{name: "date cursor reused for duration", issuedFor: SortNewest, usedFor: SortLongest, wantErr: true},
+		{name: "same column other direction", issuedFor: SortLongest, usedFor: SortShortest, wantErr: true},
+		{name: "same column other direction by date", issuedFor: SortNewest, usedFor: SortOldest, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			value, id, err := decodeCursor(encodeCursor(workout, test.issuedFor), test.usedFor)
+			if test.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidCursor)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, int64(42), id)
+			if want, ok := test.wantValue.(time.Time); ok {
+				assert.True(t, want.Equal(value.(time.Time)))
+			} else {
+				assert.Equal(t, test.wantValue, value)
+			}
+		})
+	}
+
+	for _, cursor := range []string{
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("-created_at|2025-03-14T18:30:00Z")),
+		base64.RawURLEncoding.EncodeToString([]byte("-created_at|yesterday|42")),
+		base64.RawURLEncoding.EncodeToString([]byte("-duration|long|42")),
+		base64.RawURLEncoding.EncodeToString([]byte("-created_at|2025-03-14T18:30:00Z|x")),
+	} {
+		_, _, err := decodeCursor(cursor, SortNewest)
+		assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+	}
+}
+
+func TestListWorkoutsQuery(t *testing.T) {
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	cursor := encodeCursor(&Workout{ID: 9, Duration: 30}, SortShortest)
+
+	tests := []struct {
+		name      string
+		filter    WorkoutFilter
+		wantOrder string
+		wantWhere []string
+		wantArgs  int
+		wantLimit int
+		wantErr   error
+	}{
+		{
+			name:      "defaults",
+			filter:    WorkoutFilter{UserID: 1},
+			wantOrder: "ORDER BY created_at DESC, id DESC",
+			wantArgs:  2,
+			wantLimit: DefaultListLimit,
+		},
+		{
+			name:      "oldest from a date",
+			filter:    WorkoutFilter{UserID: 1, Sort: SortOldest, From: &from, Limit: 5},
+			wantOrder: "ORDER BY created_at ASC, id ASC",
+			wantWhere: []string{"created_at >= $2"},
+			wantArgs:  3,
+			wantLimit: 5,
+		},
+		{
+			name:      "longest with a title",
+			filter:    WorkoutFilter{UserID: 1, Sort: SortLongest, Title: "push", Limit: MaxListLimit + 1},
+			wantOrder: "ORDER BY duration DESC, id DESC",
+			wantWhere: []string{"title ILIKE $2"},
+			wantArgs:  3,
+			wantLimit: DefaultListLimit,
+		},
+		{
+			name:      "shortest after a cursor",
+			filter:    WorkoutFilter{UserID: 1, Sort: SortShortest, Cursor: cursor},
+			wantOrder: "ORDER BY duration ASC, id ASC",
+			wantWhere: []string{"(duration, id) > ($2, $3)"},
+			wantArgs:  4,
+			wantLimit: DefaultListLimit,
+		},
+		{
+			name:      "all tags",
+			filter:    WorkoutFilter{UserID: 1, Tags: []string{"Legs", "heavy"}},
+			wantOrder: "ORDER BY created_at DESC, id DESC",
+			wantWhere: []string{"t.name = ANY($2)", "HAVING COUNT(*) = $3"},
+			wantArgs:  4,
+			wantLimit: DefaultListLimit,
+		},
+		{
+			name:      "any tag",
+			filter:    WorkoutFilter{UserID: 1, Tags: []string{"legs"}, TagMode: TagModeAny},
+			wantOrder: "ORDER BY created_at DESC, id DESC",
+			wantWhere: []string{"t.name = ANY($2)"},
+			wantArgs:  3,
+			wantLimit: DefaultListLimit,
+		},
+		{name: "unknown sort", filter: WorkoutFilter{Sort: "title"}, wantErr: ErrInvalidSort},
+		{name: "unknown tag mode", filter: WorkoutFilter{Tags: []string{"legs"}, TagMode: "some"}, wantErr: ErrInvalidTagMode},
+		{name: "cursor of another sort", filter: WorkoutFilter{Sort: SortLongest, Cursor: cursor}, wantErr: ErrInvalidCursor},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filter := test.filter
+			query, args, err := listWorkoutsQuery(&filter)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Contains(t, query, test.wantOrder)
+			for _, condition := range test.wantWhere {
+				assert.Contains(t, query, condition)
+			}
+			require.Len(t, args, test.wantArgs)
+			assert.Equal(t, test.wantLimit, filter.Limit)
+			assert.Equal(t, test.wantLimit+1, args[len(args)-1])
+		})
+	}
+}
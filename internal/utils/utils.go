@@ -5,6 +5,8 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -38,3 +40,35 @@ func ReadIDParam(r *http.Request) (int64, error) {
 
 	return id, nil
 }
+
+// ReadDateParam parses an optional YYYY-MM-DD query parameter.
+func ReadDateParam(r *http.Request, name string) (*time.Time, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, errors.New("invalid date")
+	}
+
+	return &date, nil
+}
+
+// ETag quotes value as a strong entity tag.
+func ETag(value string) string {
+	return `"` + value + `"`
+}
+
+// ETagMatches reports whether an If-None-Match header lists etag. "*"
+// matches any etag, and weak tags compare by their value.
+func ETagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,19 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETagMatches(t *testing.T) {
+	etag := ETag("7.3.kg")
+	assert.Equal(t, `"7.3.kg"`, etag)
+
+	assert.True(t, ETagMatches(etag, etag))
+	assert.True(t, ETagMatches(`"1.1.kg", `+etag, etag))
+	assert.True(t, ETagMatches("W/"+etag, etag))
+	assert.True(t, ETagMatches("*", etag))
+	assert.False(t, ETagMatches(`"7.2.kg"`, etag))
+	assert.False(t, ETagMatches(`"7.3.lb"`, etag))
+}
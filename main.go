@@ -24,6 +24,8 @@ func main() {
 
 	app.Logger.Println("we are runing our app")
 
+	go app.PurgeTrash(time.Hour)
+
 	r := routes.SetupRoutes(app)
 
 	server := &http.Server{
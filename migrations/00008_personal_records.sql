@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS personal_records (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    exercise_name VARCHAR(255) NOT NULL,
+    record_type VARCHAR(50) NOT NULL,
+    weight DECIMAL(10,2) NOT NULL DEFAULT 0,
+    reps INT NOT NULL DEFAULT 0,
+    value DECIMAL(12,2) NOT NULL,
+    workout_id BIGINT REFERENCES workouts(id) ON DELETE SET NULL,
+    achieved_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, exercise_name, record_type, weight)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS personal_records;
+-- +goose StatementEnd
@@ -0,0 +1,94 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS exercises (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    primary_muscles TEXT[] NOT NULL DEFAULT '{}',
+    secondary_muscles TEXT[] NOT NULL DEFAULT '{}',
+    equipment VARCHAR(50) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS exercises_owner_name_idx ON exercises (COALESCE(user_id, 0), LOWER(name));
+
+CREATE TABLE IF NOT EXISTS exercise_aliases (
+    exercise_id BIGINT NOT NULL REFERENCES exercises(id) ON DELETE CASCADE,
+    alias VARCHAR(255) NOT NULL,
+    PRIMARY KEY (exercise_id, alias)
+);
+
+ALTER TABLE workout_entries ADD COLUMN exercise_id BIGINT REFERENCES exercises(id) ON DELETE SET NULL;
+
+INSERT INTO exercises (name, primary_muscles, secondary_muscles, equipment) VALUES
+    ('Bench Press', '{chest}', '{triceps,shoulders}', 'barbell'),
+    ('Incline Bench Press', '{chest}', '{shoulders,triceps}', 'barbell'),
+    ('Dumbbell Bench Press', '{chest}', '{triceps,shoulders}', 'dumbbell'),
+    ('Overhead Press', '{shoulders}', '{triceps}', 'barbell'),
+    ('Dumbbell Shoulder Press', '{shoulders}', '{triceps}', 'dumbbell'),
+    ('Lateral Raise', '{shoulders}', '{}', 'dumbbell'),
+    ('Back Squat', '{quadriceps}', '{glutes,hamstrings,lower back}', 'barbell'),
+    ('Front Squat', '{quadriceps}', '{glutes,abs}', 'barbell'),
+    ('Leg Press', '{quadriceps}', '{glutes}', 'machine'),
+    ('Deadlift', '{hamstrings,lower back}', '{glutes,traps,forearms}', 'barbell'),
+    ('Romanian Deadlift', '{hamstrings}', '{glutes,lower back}', 'barbell'),
+    ('Lunge', '{quadriceps}', '{glutes,hamstrings}', 'dumbbell'),
+    ('Pull-up', '{lats}', '{biceps,middle back}', 'bodyweight'),
+    ('Chin-up', '{lats}', '{biceps}', 'bodyweight'),
+    ('Lat Pulldown', '{lats}', '{biceps}', 'cable'),
+    ('Barbell Row', '{middle back}', '{lats,biceps}', 'barbell'),
+    ('Seated Cable Row', '{middle back}', '{lats,biceps}', 'cable'),
+    ('Push-up', '{chest}', '{triceps,shoulders}', 'bodyweight'),
+    ('Dip', '{triceps}', '{chest,shoulders}', 'bodyweight'),
+    ('Barbell Curl', '{biceps}', '{forearms}', 'barbell'),
+    ('Dumbbell Curl', '{biceps}', '{forearms}', 'dumbbell'),
+    ('Triceps Pushdown', '{triceps}', '{}', 'cable'),
+    ('Hip Thrust', '{glutes}', '{hamstrings}', 'barbell'),
+    ('Calf Raise', '{calves}', '{}', 'machine'),
+    ('Plank', '{abs}', '{lower back}', 'bodyweight'),
+    ('Running', '{quadriceps}', '{hamstrings,calves}', 'none'),
+    ('Cycling', '{quadriceps}', '{hamstrings,calves}', 'bike'),
+    ('Rowing', '{middle back}', '{quadriceps,biceps}', 'machine');
+
+INSERT INTO exercise_aliases (exercise_id, alias)
+SELECT e.id, a.alias
+FROM exercises e
+INNER JOIN (VALUES
+    ('Bench Press', 'bench'),
+    ('Bench Press', 'bp'),
+    ('Bench Press', 'flat bench'),
+    ('Bench Press', 'barbell bench press'),
+    ('Incline Bench Press', 'incline bench'),
+    ('Dumbbell Bench Press', 'db bench'),
+    ('Overhead Press', 'ohp'),
+    ('Overhead Press', 'military press'),
+    ('Overhead Press', 'shoulder press'),
+    ('Back Squat', 'squat'),
+    ('Back Squat', 'squats'),
+    ('Deadlift', 'dl'),
+    ('Deadlift', 'conventional deadlift'),
+    ('Romanian Deadlift', 'rdl'),
+    ('Pull-up', 'pullup'),
+    ('Pull-up', 'pull ups'),
+    ('Chin-up', 'chinup'),
+    ('Barbell Row', 'bent over row'),
+    ('Push-up', 'pushup'),
+    ('Push-up', 'push ups'),
+    ('Dip', 'dips'),
+    ('Barbell Curl', 'curl'),
+    ('Barbell Curl', 'bicep curl'),
+    ('Triceps Pushdown', 'tricep pushdown'),
+    ('Running', 'run'),
+    ('Running', 'jog'),
+    ('Cycling', 'bike'),
+    ('Rowing', 'row erg')
+) AS a (name, alias) ON a.name = e.name
+WHERE e.user_id IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workout_entries DROP COLUMN IF EXISTS exercise_id;
+DROP TABLE IF EXISTS exercise_aliases;
+DROP TABLE IF EXISTS exercises;
+-- +goose StatementEnd
@@ -0,0 +1,213 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"unicode"
+
+	"github.com/pressly/goose/v3"
+)
+
+func init() {
+	goose.AddMigrationContext(upMapExerciseNames, downMapExerciseNames)
+}
+
+// The matching below is a frozen copy of the store's exercise matching at
+// the time this migration was written. It must not call into the store so
+// that later changes there cannot change what this migration does.
+
+const mapExerciseMinSimilarity = 0.75
+
+var mapExerciseQualifiers = map[string]bool{
+	"back": true, "front": true, "hack": true, "sumo": true, "box": true,
+	"incline": true, "decline": true, "flat": true, "close": true, "wide": true,
+	"reverse": true, "romanian": true, "stiff": true, "split": true, "goblet": true,
+	"seated": true, "standing": true, "lying": true, "overhead": true,
+	"barbell": true, "dumbbell": true, "cable": true, "machine": true, "smith": true,
+	"kettlebell": true, "single": true, "one": true, "leg": true, "arm": true,
+	"hip": true, "calf": true, "lat": true, "rear": true, "lateral": true,
+}
+
+type mapExercise struct {
+	id     int
+	custom bool
+	names  []string
+}
+
+// upMapExerciseNames links the free-text entries logged before the catalog
+// existed to their catalog exercise, leaving unmatched names alone.
+func upMapExerciseNames(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, `
+	SELECT DISTINCT w.user_id, we.exercise_name
+	FROM workout_entries we
+	INNER JOIN workouts w ON w.id = we.workout_id
+	WHERE we.exercise_id IS NULL
+	`)
+	if err != nil {
+		return err
+	}
+
+	type pending struct {
+		userID int
+		name   string
+	}
+	names := []pending{}
+	for rows.Next() {
+		var p pending
+		err = rows.Scan(&p.userID, &p.name)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		names = append(names, p)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	catalogs := map[int][]mapExercise{}
+	for _, p := range names {
+		catalog, ok := catalogs[p.userID]
+		if !ok {
+			catalog, err = loadMapExercises(ctx, tx, p.userID)
+			if err != nil {
+				return err
+			}
+			catalogs[p.userID] = catalog
+		}
+
+		exerciseID, ok := matchMapExercise(p.name, catalog)
+		if !ok {
+			continue
+		}
+
+		_, err = tx.ExecContext(ctx, `
+		UPDATE workout_entries SET exercise_id = $1
+		WHERE exercise_id IS NULL AND exercise_name = $2
+		AND workout_id IN (SELECT id FROM workouts WHERE user_id = $3)
+		`, exerciseID, p.name, p.userID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func downMapExerciseNames(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `UPDATE workout_entries SET exercise_id = NULL`)
+	return err
+}
+
+func loadMapExercises(ctx context.Context, tx *sql.Tx, userID int) ([]mapExercise, error) {
+	rows, err := tx.QueryContext(ctx, `
+	SELECT e.id, e.user_id IS NOT NULL, e.name, a.alias
+	FROM exercises e
+	LEFT JOIN exercise_aliases a ON a.exercise_id = e.id
+	WHERE e.user_id IS NULL OR e.user_id = $1
+	ORDER BY e.name, e.id, a.alias
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	catalog := []mapExercise{}
+	for rows.Next() {
+		var exercise mapExercise
+		var name string
+		var alias sql.NullString
+		err = rows.Scan(&exercise.id, &exercise.custom, &name, &alias)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(catalog); n == 0 || catalog[n-1].id != exercise.id {
+			exercise.names = []string{name}
+			catalog = append(catalog, exercise)
+		}
+		if alias.Valid {
+			last := &catalog[len(catalog)-1]
+			last.names = append(last.names, alias.String)
+		}
+	}
+	return catalog, rows.Err()
+}
+
+func matchMapExercise(name string, catalog []mapExercise) (int, bool) {
+	needle := strings.Fields(mapExerciseKey(name))
+	if len(needle) == 0 {
+		return 0, false
+	}
+
+	best, bestCustom, bestScore := 0, false, 0.0
+	for _, exercise := range catalog {
+		for _, candidate := range exercise.names {
+			score := mapWordSimilarity(needle, strings.Fields(mapExerciseKey(candidate)))
+			if score > bestScore || (score == bestScore && best != 0 && !bestCustom && exercise.custom) {
+				best, bestCustom, bestScore = exercise.id, exercise.custom, score
+			}
+		}
+	}
+	return best, bestScore >= mapExerciseMinSimilarity
+}
+
+func mapExerciseKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func mapWordSimilarity(a, b []string) float64 {
+	if strings.Join(a, "") == strings.Join(b, "") {
+		return 1
+	}
+	if len(a) != len(b) {
+		return 0
+	}
+
+	score := 1.0
+	for i := range a {
+		if a[i] == b[i] {
+			continue
+		}
+		if mapExerciseQualifiers[a[i]] || mapExerciseQualifiers[b[i]] {
+			return 0
+		}
+		score = min(score, mapSimilarity(a[i], b[i]))
+	}
+	return score
+}
+
+func mapSimilarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(rb)])/float64(longest)
+}
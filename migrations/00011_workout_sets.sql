@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS workout_sets (
+    id BIGSERIAL PRIMARY KEY,
+    entry_id BIGINT NOT NULL REFERENCES workout_entries(id) ON DELETE CASCADE,
+    set_number INT NOT NULL,
+    set_type VARCHAR(20) NOT NULL DEFAULT 'working',
+    reps INT,
+    weight DECIMAL(10,2),
+    rpe DECIMAL(3,1),
+    rir INT,
+    rest_seconds INT,
+    UNIQUE (entry_id, set_number),
+    CONSTRAINT valid_workout_set CHECK (
+        set_type IN ('warmup', 'working', 'drop', 'failure')
+        AND (reps IS NULL OR reps >= 0)
+        AND (weight IS NULL OR weight >= 0)
+        AND (rpe IS NULL OR rpe BETWEEN 1 AND 10)
+        AND (rir IS NULL OR rir >= 0)
+        AND (rest_seconds IS NULL OR rest_seconds >= 0)
+    )
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS workout_sets;
+-- +goose StatementEnd
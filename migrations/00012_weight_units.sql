@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN weight_unit VARCHAR(2) NOT NULL DEFAULT 'kg' CHECK (weight_unit IN ('kg', 'lb'));
+
+ALTER TABLE workout_entries ALTER COLUMN weight TYPE DECIMAL(12,4);
+ALTER TABLE workout_entries ADD COLUMN entered_unit VARCHAR(2) NOT NULL DEFAULT 'kg' CHECK (entered_unit IN ('kg', 'lb'));
+ALTER TABLE workout_sets ALTER COLUMN weight TYPE DECIMAL(12,4);
+ALTER TABLE workout_template_entries ALTER COLUMN weight TYPE DECIMAL(12,4);
+ALTER TABLE personal_records ALTER COLUMN weight TYPE DECIMAL(12,4);
+ALTER TABLE personal_records ALTER COLUMN value TYPE DECIMAL(14,4);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE personal_records ALTER COLUMN value TYPE DECIMAL(12,2);
+ALTER TABLE personal_records ALTER COLUMN weight TYPE DECIMAL(10,2);
+ALTER TABLE workout_template_entries ALTER COLUMN weight TYPE DECIMAL(10,2);
+ALTER TABLE workout_sets ALTER COLUMN weight TYPE DECIMAL(10,2);
+ALTER TABLE workout_entries DROP COLUMN IF EXISTS entered_unit;
+ALTER TABLE workout_entries ALTER COLUMN weight TYPE DECIMAL(10,2);
+ALTER TABLE users DROP COLUMN IF EXISTS weight_unit;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN body_weight DECIMAL(6,2) CHECK (body_weight > 0);
+ALTER TABLE workouts ADD COLUMN calories_estimated INT;
+ALTER TABLE workouts ADD COLUMN calories_reported INT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workouts DROP COLUMN IF EXISTS calories_reported;
+ALTER TABLE workouts DROP COLUMN IF EXISTS calories_estimated;
+ALTER TABLE users DROP COLUMN IF EXISTS body_weight;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS workout_routes (
+    workout_id BIGINT PRIMARY KEY REFERENCES workouts(id) ON DELETE CASCADE,
+    duration_seconds INT NOT NULL,
+    distance_meters DECIMAL(10,1) NOT NULL,
+    elevation_gain DECIMAL(8,1) NOT NULL DEFAULT 0,
+    avg_pace_seconds_per_km DECIMAL(8,1) NOT NULL DEFAULT 0,
+    avg_speed_kmh DECIMAL(6,2) NOT NULL DEFAULT 0,
+    avg_heart_rate INT,
+    splits JSONB NOT NULL DEFAULT '[]',
+    route JSONB NOT NULL
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS workout_routes;
+-- +goose StatementEnd
@@ -0,0 +1,57 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workout_entries DROP CONSTRAINT IF EXISTS valid_workout_entry;
+ALTER TABLE workout_entries ALTER COLUMN reps DROP NOT NULL;
+ALTER TABLE workout_entries ALTER COLUMN weight DROP NOT NULL;
+ALTER TABLE workout_entries ALTER COLUMN duration DROP NOT NULL;
+
+ALTER TABLE workout_entries ADD COLUMN entry_type VARCHAR(20) NOT NULL DEFAULT 'strength';
+ALTER TABLE workout_entries ADD COLUMN distance DECIMAL(10,1);
+ALTER TABLE workout_entries ADD COLUMN avg_heart_rate INT;
+ALTER TABLE workout_entries ADD COLUMN rounds INT;
+ALTER TABLE workout_entries ADD COLUMN work_seconds INT;
+ALTER TABLE workout_entries ADD COLUMN rest_seconds INT;
+
+ALTER TABLE workout_entries ADD CONSTRAINT valid_workout_entry CHECK (
+    sets > 0
+    AND (weight IS NULL OR weight >= 0)
+    AND (duration IS NULL OR duration >= 0)
+    AND CASE entry_type
+        WHEN 'strength' THEN reps > 0 AND distance IS NULL AND rounds IS NULL AND work_seconds IS NULL
+        WHEN 'cardio' THEN reps IS NULL AND weight IS NULL AND (distance IS NOT NULL OR duration IS NOT NULL) AND (distance IS NULL OR distance >= 0)
+        WHEN 'timed' THEN reps IS NULL AND distance IS NULL AND duration > 0
+        WHEN 'interval' THEN reps IS NULL AND weight IS NULL AND rounds > 0 AND work_seconds > 0 AND (rest_seconds IS NULL OR rest_seconds >= 0)
+        ELSE FALSE
+    END
+);
+
+ALTER TABLE workout_template_entries ADD COLUMN entry_type VARCHAR(20) NOT NULL DEFAULT 'strength';
+ALTER TABLE workout_template_entries ADD COLUMN distance DECIMAL(10,1);
+ALTER TABLE workout_template_entries ADD COLUMN rounds INT;
+ALTER TABLE workout_template_entries ADD COLUMN work_seconds INT;
+ALTER TABLE workout_template_entries ADD COLUMN rest_seconds INT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workout_template_entries DROP COLUMN IF EXISTS rest_seconds;
+ALTER TABLE workout_template_entries DROP COLUMN IF EXISTS work_seconds;
+ALTER TABLE workout_template_entries DROP COLUMN IF EXISTS rounds;
+ALTER TABLE workout_template_entries DROP COLUMN IF EXISTS distance;
+ALTER TABLE workout_template_entries DROP COLUMN IF EXISTS entry_type;
+
+DELETE FROM workout_entries WHERE entry_type <> 'strength';
+ALTER TABLE workout_entries DROP CONSTRAINT IF EXISTS valid_workout_entry;
+ALTER TABLE workout_entries DROP COLUMN IF EXISTS rest_seconds;
+ALTER TABLE workout_entries DROP COLUMN IF EXISTS work_seconds;
+ALTER TABLE workout_entries DROP COLUMN IF EXISTS rounds;
+ALTER TABLE workout_entries DROP COLUMN IF EXISTS avg_heart_rate;
+ALTER TABLE workout_entries DROP COLUMN IF EXISTS distance;
+ALTER TABLE workout_entries DROP COLUMN IF EXISTS entry_type;
+UPDATE workout_entries SET weight = 0 WHERE weight IS NULL;
+UPDATE workout_entries SET duration = 0 WHERE duration IS NULL;
+ALTER TABLE workout_entries ALTER COLUMN duration SET NOT NULL;
+ALTER TABLE workout_entries ALTER COLUMN weight SET NOT NULL;
+ALTER TABLE workout_entries ALTER COLUMN reps SET NOT NULL;
+ALTER TABLE workout_entries ADD CONSTRAINT valid_workout_entry CHECK (sets > 0 AND reps > 0 AND weight >= 0 AND duration >= 0);
+-- +goose StatementEnd
@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workout_entries ADD COLUMN group_label VARCHAR(20);
+ALTER TABLE workout_entries ADD COLUMN group_type VARCHAR(20);
+ALTER TABLE workout_entries ADD COLUMN group_rounds INT;
+ALTER TABLE workout_entries ADD COLUMN group_rest_seconds INT;
+
+ALTER TABLE workout_entries ADD CONSTRAINT valid_entry_group CHECK (
+    (group_label IS NULL AND group_type IS NULL AND group_rounds IS NULL AND group_rest_seconds IS NULL)
+    OR (
+        group_label IS NOT NULL
+        AND group_type IN ('superset', 'circuit')
+        AND group_rounds > 0
+        AND (group_rest_seconds IS NULL OR group_rest_seconds >= 0)
+    )
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workout_entries DROP CONSTRAINT IF EXISTS valid_entry_group;
+ALTER TABLE workout_entries DROP COLUMN IF EXISTS group_rest_seconds;
+ALTER TABLE workout_entries DROP COLUMN IF EXISTS group_rounds;
+ALTER TABLE workout_entries DROP COLUMN IF EXISTS group_type;
+ALTER TABLE workout_entries DROP COLUMN IF EXISTS group_label;
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS goals (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    goal_type VARCHAR(20) NOT NULL,
+    target DECIMAL(10,2) NOT NULL CHECK (target > 0),
+    exercise_name VARCHAR(255),
+    period VARCHAR(10),
+    deadline DATE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT valid_goal CHECK (
+        CASE goal_type
+            WHEN 'frequency' THEN period IN ('week', 'month') AND exercise_name IS NULL
+            WHEN 'volume' THEN period IN ('week', 'month') AND exercise_name IS NULL
+            WHEN 'lift' THEN period IS NULL AND exercise_name IS NOT NULL
+            ELSE FALSE
+        END
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_goals_user_id ON goals(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS goals;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 00002 named the column calories, while the store has always read and
+-- written calories_burned
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_name = 'workouts' AND column_name = 'calories') THEN
+        ALTER TABLE workouts RENAME COLUMN calories TO calories_burned;
+    END IF;
+END $$;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE workouts RENAME COLUMN calories_burned TO calories;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+-- personal records are kept under the catalog name, so lift goals set
+-- before goals were resolved through the catalog point at the exercise by
+-- its exact name or alias
+UPDATE goals g
+SET exercise_name = (
+    SELECT e.name
+    FROM exercises e
+    LEFT JOIN exercise_aliases a ON a.exercise_id = e.id
+    WHERE (e.user_id IS NULL OR e.user_id = g.user_id)
+    AND LOWER(REGEXP_REPLACE(TRIM(g.exercise_name), '\s+', ' ', 'g')) IN (LOWER(e.name), a.alias)
+    ORDER BY e.user_id NULLS LAST, e.id
+    LIMIT 1
+)
+WHERE g.goal_type = 'lift'
+AND EXISTS (
+    SELECT 1
+    FROM exercises e
+    LEFT JOIN exercise_aliases a ON a.exercise_id = e.id
+    WHERE (e.user_id IS NULL OR e.user_id = g.user_id)
+    AND LOWER(REGEXP_REPLACE(TRIM(g.exercise_name), '\s+', ' ', 'g')) IN (LOWER(e.name), a.alias)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- the names goals were created with are not kept
+SELECT 1;
+-- +goose StatementEnd
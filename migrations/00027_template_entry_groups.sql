@@ -0,0 +1,52 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE workout_template_entries ADD COLUMN exercise_id BIGINT REFERENCES exercises(id) ON DELETE SET NULL;
+ALTER TABLE workout_template_entries ADD COLUMN avg_heart_rate INT;
+ALTER TABLE workout_template_entries ADD COLUMN group_label VARCHAR(20);
+ALTER TABLE workout_template_entries ADD COLUMN group_type VARCHAR(20);
+ALTER TABLE workout_template_entries ADD COLUMN group_rounds INT;
+ALTER TABLE workout_template_entries ADD COLUMN group_rest_seconds INT;
+
+ALTER TABLE workout_template_entries ADD CONSTRAINT valid_template_entry_group CHECK (
+    (group_label IS NULL AND group_type IS NULL AND group_rounds IS NULL AND group_rest_seconds IS NULL)
+    OR (
+        group_label IS NOT NULL
+        AND group_type IN ('superset', 'circuit')
+        AND group_rounds > 0
+        AND (group_rest_seconds IS NULL OR group_rest_seconds >= 0)
+    )
+);
+
+CREATE TABLE IF NOT EXISTS workout_template_sets (
+    id BIGSERIAL PRIMARY KEY,
+    template_entry_id BIGINT NOT NULL REFERENCES workout_template_entries(id) ON DELETE CASCADE,
+    set_number INT NOT NULL,
+    set_type VARCHAR(20) NOT NULL DEFAULT 'working',
+    reps INT,
+    weight DECIMAL(12,4),
+    rpe DECIMAL(3,1),
+    rir INT,
+    rest_seconds INT,
+    UNIQUE (template_entry_id, set_number),
+    CONSTRAINT valid_template_set CHECK (
+        set_type IN ('warmup', 'working', 'drop', 'failure')
+        AND (reps IS NULL OR reps >= 0)
+        AND (weight IS NULL OR weight >= 0)
+        AND (rpe IS NULL OR rpe BETWEEN 1 AND 10)
+        AND (rir IS NULL OR rir >= 0)
+        AND (rest_seconds IS NULL OR rest_seconds >= 0)
+    )
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS workout_template_sets;
+ALTER TABLE workout_template_entries DROP CONSTRAINT IF EXISTS valid_template_entry_group;
+ALTER TABLE workout_template_entries DROP COLUMN IF EXISTS group_rest_seconds;
+ALTER TABLE workout_template_entries DROP COLUMN IF EXISTS group_rounds;
+ALTER TABLE workout_template_entries DROP COLUMN IF EXISTS group_type;
+ALTER TABLE workout_template_entries DROP COLUMN IF EXISTS group_label;
+ALTER TABLE workout_template_entries DROP COLUMN IF EXISTS avg_heart_rate;
+ALTER TABLE workout_template_entries DROP COLUMN IF EXISTS exercise_id;
+-- +goose StatementEnd